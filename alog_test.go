@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 )
@@ -60,7 +61,7 @@ func ExampleWithCaller() {
 
 	l.Print(ctx, "test")
 	// Output:
-	// alog_test.go:61 test
+	// alog_test.go:62 test
 }
 
 func TestOverrideTimestamp(t *testing.T) {
@@ -123,3 +124,107 @@ func TestStdLogger(t *testing.T) {
 		t.Fatalf("want: %#q, got: %#q", want, got)
 	}
 }
+
+func TestWith(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	l := New(WithEmitter(EmitterFunc(func(ctx context.Context, e *Entry) {
+		fmt.Fprintf(buf, "%v %v %s\n", e.Tags, e.STags, e.Msg)
+	})))
+
+	billing := l.With("component", "billing", "unpaired")
+	worker := billing.With("worker", "7").WithStructured(STag{Key: "n", Val: 1})
+
+	ctx := AddTags(context.Background(), "worker", "8")
+	billing.Print(ctx, "billing")
+	worker.Print(ctx, "worker")
+	l.Print(ctx, "parent")
+
+	want := "[[component billing] [worker 8]] [] billing\n" +
+		"[[component billing] [worker 8]] [{n 1}] worker\n" +
+		"[[worker 8]] [] parent\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("want: %#q, got: %#q", want, got)
+	}
+}
+
+func TestWithNil(t *testing.T) {
+	t.Parallel()
+	var l *Logger
+	if l.With("a", "b").WithStructured(STag{Key: "c"}) != nil {
+		t.Fatal("With on a nil *Logger returned a non-nil *Logger")
+	}
+	l.With("a", "b").Print(context.Background(), "this shouldn't explode")
+}
+
+func TestStack(t *testing.T) {
+	t.Parallel()
+	var stacks [][]Frame
+	l := New(WithEmitter(EmitterFunc(func(ctx context.Context, e *Entry) {
+		stacks = append(stacks, e.Stack)
+	})), WithStack(LevelError))
+
+	ctx := context.Background()
+	l.Print(WithLevel(ctx, LevelWarning), "no stack")
+	l.Print(WithLevel(ctx, LevelError), "stack")
+	l.Print(CaptureStack(ctx), "stack")
+
+	if len(stacks) != 3 || stacks[0] != nil {
+		t.Fatalf("unexpected stacks: %v", stacks)
+	}
+	for _, stack := range stacks[1:] {
+		if len(stack) == 0 || stack[0].Function != "github.com/vimeo/alog/v3.TestStack" || filepath.Base(stack[0].File) != "alog_test.go" {
+			t.Errorf("stack doesn't start at the caller: %v", stack)
+		}
+	}
+}
+
+// outputWithPC logs msg with the pc of its caller, the way a slog.Handler
+// would.
+func outputWithPC(l *Logger, msg string) {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	l.OutputPC(context.Background(), pcs[0], msg)
+}
+
+func TestOutputPCStack(t *testing.T) {
+	t.Parallel()
+	var e Entry
+	l := New(WithEmitter(EmitterFunc(func(ctx context.Context, entry *Entry) {
+		e = *entry
+	})), WithCaller(), WithStack(LevelNone))
+
+	outputWithPC(l, "test")
+
+	if len(e.Stack) == 0 || e.Stack[0].Function != "github.com/vimeo/alog/v3.TestOutputPCStack" || e.Stack[0].Line != e.Line {
+		t.Errorf("stack doesn't start at the pc: %v, %s:%d", e.Stack, e.File, e.Line)
+	}
+}
+
+func TestTagScopes(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	l := New(WithEmitter(EmitterFunc(func(ctx context.Context, e *Entry) {
+		e.Resolve()
+		fmt.Fprintf(buf, "%v %v %s\n", e.Tags, e.STags, e.Msg)
+	})))
+
+	ctx := AddTags(context.Background(), "a", "1", "b", "2", "c", "3")
+	ctx = AddStructuredTags(ctx, STag{Key: "s", Val: 1}, STag{Key: "s", Val: 2})
+	ctx = AddLazyTags(ctx, LazyTag{Key: "lazy", Val: func() string { return "v" }})
+
+	l.Print(AddTags(ctx, "a", "4"), "add")
+	l.Print(ReplaceTags(ctx, "a", "4", "d", "5"), "replace")
+	l.Print(RemoveTags(ctx, "b", "s", "lazy", "missing"), "remove")
+	l.Print(AddTags(WithoutTags(ctx), "e", "6"), "scope")
+	l.Print(ctx, "unchanged")
+
+	want := "[[b 2] [c 3] [a 4] [lazy v]] [{s 2}] add\n" +
+		"[[a 4] [b 2] [c 3] [d 5] [lazy v]] [{s 2}] replace\n" +
+		"[[a 1] [c 3]] [] remove\n" +
+		"[[e 6]] [] scope\n" +
+		"[[a 1] [b 2] [c 3] [lazy v]] [{s 2}] unchanged\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("want: %#q, got: %#q", want, got)
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+	"github.com/vimeo/alog/v3/emitter/jsonlog"
+	"github.com/vimeo/alog/v3/emitter/textlog"
+)
+
+// Formats of the emitters that FromEnv and Config build.
+const (
+	FormatGKE  = "gkelog"
+	FormatJSON = "jsonlog"
+	FormatText = "textlog"
+)
+
+// emitterSpec describes one of the emitters that FromEnv and Config build.
+type emitterSpec struct {
+	format        string
+	output        string // stdout, stderr, a file path, or "" for the default
+	caller        bool
+	shortFile     bool
+	dateFormat    string
+	hasDateFormat bool
+	utc           bool
+}
+
+// check reports the first problem with s, naming the setting at fault.
+func (s *emitterSpec) check() (setting string, err error) {
+	switch s.format {
+	case FormatGKE:
+		if s.hasDateFormat {
+			return "DATE_FORMAT", errors.New("not supported by gkelog")
+		}
+	case FormatJSON, FormatText:
+	default:
+		return "FORMAT", fmt.Errorf("unknown format %q", s.format)
+	}
+	return "", nil
+}
+
+// build returns the emitter described by s, which must have passed check, and
+// the file it writes to, if it opened one.
+func (s *emitterSpec) build(o *Options) (alog.Emitter, *os.File, error) {
+	var w io.Writer
+	var f *os.File
+	switch s.output {
+	case "":
+		if s.format != FormatGKE {
+			w = o.stderr
+		}
+	case "stdout":
+		w = o.stdout
+	case "stderr":
+		w = o.stderr
+	default:
+		var err error
+		f, err = os.OpenFile(s.output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+		w = f
+	}
+
+	switch s.format {
+	case FormatGKE:
+		var opts []gkelog.Option
+		if w != nil {
+			opts = append(opts, gkelog.WithWriter(w))
+		}
+		if s.shortFile {
+			opts = append(opts, gkelog.WithShortFile())
+		}
+		return gkelog.Emitter(opts...), f, nil
+	case FormatJSON:
+		var opts []jsonlog.Option
+		if s.shortFile {
+			opts = append(opts, jsonlog.WithShortFile())
+		} else if s.caller {
+			opts = append(opts, jsonlog.WithFile())
+		}
+		if s.hasDateFormat {
+			opts = append(opts, jsonlog.WithDateFormat(s.dateFormat))
+		}
+		if s.utc {
+			opts = append(opts, jsonlog.WithUTC())
+		}
+		return jsonlog.Emitter(w, opts...), f, nil
+	default:
+		var opts []textlog.Option
+		if s.shortFile {
+			opts = append(opts, textlog.WithShortFile())
+		} else if s.caller {
+			opts = append(opts, textlog.WithFile())
+		}
+		if s.hasDateFormat {
+			opts = append(opts, textlog.WithDateFormat(s.dateFormat))
+		}
+		if s.utc {
+			opts = append(opts, textlog.WithUTC())
+		}
+		return textlog.Emitter(w, opts...), f, nil
+	}
+}
@@ -0,0 +1,149 @@
+// Package config builds configured Loggers from the environment or from a
+// file, so that services don't each have to wire up emitters and their options
+// in main.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// FromEnv returns a Logger configured by environment variables. With the
+// default prefix, they are:
+//
+//	ALOG_FORMAT       gkelog, jsonlog or textlog; the default is textlog
+//	ALOG_OUTPUT       stdout, stderr, or the path of a file to append to; the
+//	                  default is stderr, or gkelog's own default
+//	ALOG_LEVEL        the minimum level of the entries written, parsed with
+//	                  alog.ParseLevel; entries without a level are always
+//	                  written, and a level set with alog.WithMinLevel takes
+//	                  precedence
+//	ALOG_CALLER       whether to record and write the caller of each entry
+//	ALOG_SHORTFILE    whether to write only the file name of the caller;
+//	                  implies ALOG_CALLER
+//	ALOG_DATE_FORMAT  the layout of timestamps, for jsonlog and textlog
+//	ALOG_UTC          whether to write timestamps in UTC, for jsonlog and
+//	                  textlog; gkelog always does
+//	ALOG_TAGS         tags added to every entry, as key=value pairs
+//	                  separated by commas
+//
+// Booleans are parsed with strconv.ParseBool. Unset and empty variables take
+// their defaults. All the invalid variables are reported in the error.
+//
+// The returned function closes the file given as the output, if there is one.
+// Call it once the Logger is no longer used.
+func FromEnv(opt ...Option) (logger *alog.Logger, close func() error, err error) {
+	o := new(Options)
+	for _, option := range opt {
+		option(o)
+	}
+	o.setDefaults()
+
+	e := env{o: o}
+	spec := emitterSpec{
+		format: e.str("FORMAT", FormatText),
+		output: e.str("OUTPUT", ""),
+	}
+	level := e.level("LEVEL")
+	spec.caller = e.bool("CALLER")
+	spec.shortFile = e.bool("SHORTFILE")
+	spec.dateFormat, spec.hasDateFormat = e.lookup("DATE_FORMAT")
+	spec.utc = e.bool("UTC")
+	tags := e.tags("TAGS")
+	spec.caller = spec.caller || spec.shortFile
+
+	if name, err := spec.check(); err != nil {
+		e.fail(name, err)
+	}
+	if e.err != nil {
+		return nil, nil, e.err
+	}
+
+	em, f, err := spec.build(o)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config: %sOUTPUT: %w", o.prefix, err)
+	}
+	close = func() error { return nil }
+	if f != nil {
+		close = f.Close
+	}
+
+	opts := []alog.Option{alog.WithEmitter(em)}
+	if level != alog.LevelNone {
+		opts = append(opts, alog.WithFilter(alog.MinLevelFilter(level)))
+	}
+	if spec.caller {
+		opts = append(opts, alog.WithCaller())
+	}
+	opts = append(opts, o.extra...)
+	return alog.New(opts...).With(tags...), close, nil
+}
+
+// env looks up the variables for FromEnv, collecting the errors.
+type env struct {
+	o   *Options
+	err error
+}
+
+func (e *env) lookup(name string) (string, bool) {
+	v, ok := e.o.lookup(e.o.prefix + name)
+	return v, ok && v != ""
+}
+
+func (e *env) fail(name string, err error) {
+	e.err = errors.Join(e.err, fmt.Errorf("config: %s%s: %w", e.o.prefix, name, err))
+}
+
+func (e *env) str(name, def string) string {
+	if v, ok := e.lookup(name); ok {
+		return v
+	}
+	return def
+}
+
+func (e *env) bool(name string) bool {
+	v, ok := e.lookup(name)
+	if !ok {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.fail(name, fmt.Errorf("invalid boolean %q", v))
+	}
+	return b
+}
+
+func (e *env) level(name string) alog.Level {
+	v, ok := e.lookup(name)
+	if !ok {
+		return alog.LevelNone
+	}
+	l, err := alog.ParseLevel(v)
+	if err != nil {
+		e.fail(name, err)
+	}
+	return l
+}
+
+// tags parses key=value pairs separated by commas into the pairs for
+// Logger.With.
+func (e *env) tags(name string) []string {
+	v, ok := e.lookup(name)
+	if !ok {
+		return nil
+	}
+	var pairs []string
+	for _, tag := range strings.Split(v, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok || key == "" {
+			e.fail(name, fmt.Errorf("invalid tag %q, want key=value", tag))
+			continue
+		}
+		pairs = append(pairs, key, value)
+	}
+	return pairs
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+var zeroTimeOpt = WithLoggerOptions(alog.OverrideTimestamp(func() time.Time { return time.Time{} }))
+
+func mapLookup(env map[string]string) Option {
+	return WithLookup(func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	})
+}
+
+func TestFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		env    map[string]string
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "defaults",
+			stderr: "INFO      [k=v] hello\n",
+		},
+		{
+			name: "level",
+			env:  map[string]string{"ALOG_LEVEL": "Warning"},
+		},
+		{
+			name:   "tags",
+			env:    map[string]string{"ALOG_TAGS": "svc=api, env=prod", "ALOG_OUTPUT": "stdout"},
+			stdout: "INFO      [svc=api env=prod k=v] hello\n",
+		},
+		{
+			name:   "shortfile",
+			env:    map[string]string{"ALOG_SHORTFILE": "true"},
+			stderr: "INFO      env_test.go:73: [k=v] hello\n",
+		},
+		{
+			name: "jsonlog",
+			env: map[string]string{
+				"ALOG_FORMAT":      "jsonlog",
+				"ALOG_SHORTFILE":   "1",
+				"ALOG_DATE_FORMAT": "2006",
+				"ALOG_UTC":         "1",
+			},
+			stderr: `{"timestamp":"0001", "level":"info", "caller":"env_test.go:73", "tags":{"k":"v"}, "message":"hello"}` + "\n",
+		},
+		{
+			name:   "gkelog",
+			env:    map[string]string{"ALOG_FORMAT": "gkelog", "ALOG_OUTPUT": "stdout"},
+			stdout: `{"time":"0001-01-01T00:00:00Z", "severity":"INFO", "k":"v", "message":"hello"}` + "\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			l, closeLog, err := FromEnv(mapLookup(tc.env), WithStdio(stdout, stderr), zeroTimeOpt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeLog()
+			ctx := alog.AddTags(alog.WithLevel(context.Background(), alog.LevelInfo), "k", "v")
+			l.Print(ctx, "hello")
+			if got := stdout.String(); got != tc.stdout {
+				t.Errorf("stdout:\ngot:\n%s\nwant:\n%s", got, tc.stdout)
+			}
+			if got := stderr.String(); got != tc.stderr {
+				t.Errorf("stderr:\ngot:\n%s\nwant:\n%s", got, tc.stderr)
+			}
+		})
+	}
+}
+
+func TestFromEnvLevel(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	l, closeLog, err := FromEnv(mapLookup(map[string]string{"ALOG_LEVEL": "info"}), WithStdio(nil, stderr), zeroTimeOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeLog()
+
+	resolved := 0
+	ctx := alog.AddLazyTags(context.Background(), alog.LazyTag{Key: "lazy", Val: func() string {
+		resolved++
+		return "x"
+	}})
+	l.Print(alog.WithLevel(ctx, alog.LevelDebug), "dropped")
+	l.Print(alog.WithLevel(ctx, alog.LevelInfo), "kept")
+
+	if got, want := stderr.String(), "INFO      [lazy=x] kept\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if resolved != 1 {
+		t.Errorf("lazy tag resolved %d times, want 1", resolved)
+	}
+}
+
+func TestFromEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	env := map[string]string{"ALOG_OUTPUT": path}
+	for i := 0; i < 2; i++ {
+		l, closeLog, err := FromEnv(mapLookup(env), zeroTimeOpt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Print(context.Background(), "hello")
+		if err := closeLog(); err != nil {
+			t.Fatal(err)
+		}
+		if err := closeLog(); err == nil {
+			t.Error("closed the file twice")
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "hello\nhello\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFromEnvErrors(t *testing.T) {
+	env := map[string]string{
+		"APP_FORMAT": "xml",
+		"APP_LEVEL":  "loud",
+		"APP_CALLER": "maybe",
+		"APP_TAGS":   "a=b,c",
+		"ALOG_LEVEL": "loud", // ignored with the APP_ prefix
+	}
+	_, _, err := FromEnv(mapLookup(env), WithPrefix("APP_"))
+	if err == nil {
+		t.Fatal("no error")
+	}
+	got := strings.Split(err.Error(), "\n")
+	want := []string{
+		`config: APP_LEVEL: unknown level "loud"`,
+		`config: APP_CALLER: invalid boolean "maybe"`,
+		`config: APP_TAGS: invalid tag "c", want key=value`,
+		`config: APP_FORMAT: unknown format "xml"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	env = map[string]string{"ALOG_FORMAT": "gkelog", "ALOG_DATE_FORMAT": "2006"}
+	if _, _, err := FromEnv(mapLookup(env)); err == nil || err.Error() != "config: ALOG_DATE_FORMAT: not supported by gkelog" {
+		t.Errorf("got %v", err)
+	}
+}
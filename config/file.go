@@ -0,0 +1,436 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/redact"
+	"github.com/vimeo/alog/v3/emitter/sampling"
+)
+
+// Config describes where a Logger's entries go, and which of them are kept. It
+// is usually read from a file with Load; see there for an example.
+//
+// The fields have both json and yaml struct tags, so a Config can be decoded
+// with encoding/json or with a YAML package; see WithUnmarshal.
+type Config struct {
+	// Level is the minimum level of the entries written, parsed with
+	// alog.ParseLevel. Entries without a level are always written, and a
+	// level set on the context with alog.WithMinLevel takes precedence.
+	Level string `json:"level,omitempty" yaml:"level,omitempty"`
+
+	// VModule overrides Level for the callers in particular files or
+	// packages, in the syntax of alog.VModule.Set, such as
+	// "billing=debug,cache/*=error".
+	VModule string `json:"vmodule,omitempty" yaml:"vmodule,omitempty"`
+
+	// Tags are added to every entry, ahead of the entry's own tags. A tag
+	// of the entry with the same key takes precedence.
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	// Redact lists the redaction rules applied to every entry. See the
+	// redact package.
+	Redact []RedactConfig `json:"redact,omitempty" yaml:"redact,omitempty"`
+
+	// Sampling, if set, limits how often similar entries are written. See
+	// the sampling package.
+	Sampling *SamplingConfig `json:"sampling,omitempty" yaml:"sampling,omitempty"`
+
+	// Emitters are the destinations of the entries. Each one is passed
+	// every entry its filters accept. At least one is required.
+	Emitters []EmitterConfig `json:"emitters" yaml:"emitters"`
+}
+
+// EmitterConfig describes one of the destinations of a Config.
+type EmitterConfig struct {
+	// Format is gkelog, jsonlog or textlog.
+	Format string `json:"format" yaml:"format"`
+
+	// Output is stdout, stderr, or the path of a file to append to. The
+	// default is stderr, or gkelog's own default.
+	Output string `json:"output,omitempty" yaml:"output,omitempty"`
+
+	// Caller is whether to write the caller of each entry, and ShortFile
+	// whether to write only the file name; ShortFile implies Caller.
+	Caller    bool `json:"caller,omitempty" yaml:"caller,omitempty"`
+	ShortFile bool `json:"short_file,omitempty" yaml:"short_file,omitempty"`
+
+	// DateFormat is the layout of timestamps, and UTC whether to write them
+	// in UTC. Neither is supported by gkelog.
+	DateFormat string `json:"date_format,omitempty" yaml:"date_format,omitempty"`
+	UTC        bool   `json:"utc,omitempty" yaml:"utc,omitempty"`
+
+	// Level, if set, is the minimum level of the entries passed to this
+	// emitter, on top of Config.Level. Unlike Config.Level, entries without
+	// a level are rejected.
+	Level string `json:"level,omitempty" yaml:"level,omitempty"`
+
+	// Tags, if set, restricts this emitter to entries with each of the
+	// tags, which must have one of the listed values if any are. See
+	// alog.TagFilter.
+	Tags map[string][]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	// Files, if set, restricts this emitter to entries logged from files
+	// matching the pattern. See alog.CallerFilter.
+	Files string `json:"files,omitempty" yaml:"files,omitempty"`
+}
+
+// RedactConfig describes a redaction rule. Exactly one of Key, Value and
+// Fields must be set.
+type RedactConfig struct {
+	// Key makes a redact.KeyRule with this pattern.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+
+	// Value makes a redact.ValueRule with this regular expression.
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+
+	// Fields makes a redact.FieldRule.
+	Fields bool `json:"fields,omitempty" yaml:"fields,omitempty"`
+
+	// Action is drop, mask or hmac. For mask, Mask is the replacement,
+	// "[REDACTED]" by default. For hmac, HMACKey is the key.
+	Action  string `json:"action" yaml:"action"`
+	Mask    string `json:"mask,omitempty" yaml:"mask,omitempty"`
+	HMACKey string `json:"hmac_key,omitempty" yaml:"hmac_key,omitempty"`
+}
+
+// SamplingConfig describes the sampling of a Config. Exactly one of
+// Thereafter and Rate must be set.
+type SamplingConfig struct {
+	// Interval is the length of the sampling window, parsed with
+	// time.ParseDuration. The default is sampling.DefaultInterval.
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+
+	// Key is what entries are grouped by: caller, the default, or message.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+
+	// First and Thereafter make a sampling.FirstThenEvery policy.
+	First      uint64 `json:"first,omitempty" yaml:"first,omitempty"`
+	Thereafter uint64 `json:"thereafter,omitempty" yaml:"thereafter,omitempty"`
+
+	// Rate and Burst make a sampling.TokenBucket policy.
+	Rate  float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty" yaml:"burst,omitempty"`
+
+	// ExemptLevel, if set, exempts entries of at least this level from
+	// sampling.
+	ExemptLevel string `json:"exempt_level,omitempty" yaml:"exempt_level,omitempty"`
+}
+
+// DefaultMask is the replacement used by mask redaction rules that don't set
+// one.
+const DefaultMask = "[REDACTED]"
+
+// pipeline is the emitter built from a Config, and what has to be closed once
+// it's no longer used. The Logger applies filter, if not nil, before entries
+// reach the emitter.
+type pipeline struct {
+	filter  alog.Filter
+	emitter alog.Emitter
+	closers []io.Closer
+}
+
+// Close closes what the pipeline opened, in reverse order, so that the sampling
+// emitter's last summaries are written before the files are closed.
+func (p *pipeline) Close() error {
+	var err error
+	for i := len(p.closers) - 1; i >= 0; i-- {
+		err = errors.Join(err, p.closers[i].Close())
+	}
+	return err
+}
+
+// build returns the pipeline described by c. Entries go through the level
+// filter, in the Logger, then get the static tags, then are redacted and
+// sampled, before being passed to the emitters. Nothing is left open if c is
+// invalid.
+func (c *Config) build(o *Options) (_ *pipeline, err error) {
+	p := new(pipeline)
+	defer func() {
+		if err != nil {
+			p.Close()
+		}
+	}()
+
+	if len(c.Emitters) == 0 {
+		return nil, errors.New("no emitters")
+	}
+	branches := make([]alog.Branch, len(c.Emitters))
+	for i := range c.Emitters {
+		b, err := c.Emitters[i].branch(o, p)
+		if err != nil {
+			return nil, fmt.Errorf("emitters[%d]: %w", i, err)
+		}
+		branches[i] = b
+	}
+	em := branches[0].Emitter
+	if len(branches) > 1 || branches[0].Filter != nil {
+		em = alog.MultiEmitter(branches...)
+	}
+
+	if c.Sampling != nil {
+		s, err := c.Sampling.emitter(em)
+		if err != nil {
+			return nil, fmt.Errorf("sampling: %w", err)
+		}
+		p.closers = append(p.closers, s)
+		em = s
+	}
+
+	if len(c.Redact) > 0 {
+		rules := make([]redact.Rule, len(c.Redact))
+		for i := range c.Redact {
+			r, err := c.Redact[i].rule()
+			if err != nil {
+				return nil, fmt.Errorf("redact[%d]: %w", i, err)
+			}
+			rules[i] = r
+		}
+		em = redact.Emitter(em, rules...)
+	}
+
+	if len(c.Tags) > 0 {
+		em = tagEmitter(em, c.Tags)
+	}
+
+	level := alog.LevelNone
+	if c.Level != "" {
+		if level, err = alog.ParseLevel(c.Level); err != nil {
+			return nil, fmt.Errorf("level: %w", err)
+		}
+	}
+	var vm *alog.VModule
+	if c.VModule != "" {
+		if vm, err = alog.ParseVModule(c.VModule); err != nil {
+			return nil, err
+		}
+	}
+	if level != alog.LevelNone || vm != nil {
+		p.filter = levelFilter(level, vm)
+	}
+
+	p.emitter = em
+	return p, nil
+}
+
+// branch returns the branch described by c, adding the file it opens, if any,
+// to p.
+func (c *EmitterConfig) branch(o *Options, p *pipeline) (alog.Branch, error) {
+	spec := emitterSpec{
+		format:        c.Format,
+		output:        c.Output,
+		caller:        c.Caller || c.ShortFile,
+		shortFile:     c.ShortFile,
+		dateFormat:    c.DateFormat,
+		hasDateFormat: c.DateFormat != "",
+		utc:           c.UTC,
+	}
+	if name, err := spec.check(); err != nil {
+		return alog.Branch{}, fmt.Errorf("%s: %w", strings.ToLower(name), err)
+	}
+	if spec.format == FormatGKE && spec.utc {
+		return alog.Branch{}, errors.New("utc: not supported by gkelog")
+	}
+
+	var filters []alog.Filter
+	if c.Level != "" {
+		level, err := alog.ParseLevel(c.Level)
+		if err != nil {
+			return alog.Branch{}, fmt.Errorf("level: %w", err)
+		}
+		filters = append(filters, alog.LevelFilter(level))
+	}
+	keys := make([]string, 0, len(c.Tags))
+	for key := range c.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		filters = append(filters, alog.TagFilter(key, c.Tags[key]...))
+	}
+	if c.Files != "" {
+		if err := checkPattern(c.Files); err != nil {
+			return alog.Branch{}, fmt.Errorf("files: %w", err)
+		}
+		filters = append(filters, alog.CallerFilter(c.Files))
+	}
+
+	em, f, err := spec.build(o)
+	if err != nil {
+		return alog.Branch{}, fmt.Errorf("output: %w", err)
+	}
+	if f != nil {
+		p.closers = append(p.closers, f)
+	}
+
+	b := alog.Branch{Emitter: em}
+	switch len(filters) {
+	case 0:
+	case 1:
+		b.Filter = filters[0]
+	default:
+		b.Filter = func(ctx context.Context, e *alog.Entry) bool {
+			for _, f := range filters {
+				if !f(ctx, e) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return b, nil
+}
+
+// checkPattern returns an error if pattern isn't valid for path.Match.
+func checkPattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+func (c *RedactConfig) rule() (redact.Rule, error) {
+	var a redact.Action
+	switch c.Action {
+	case "drop":
+		a = redact.Drop()
+	case "mask":
+		mask := c.Mask
+		if mask == "" {
+			mask = DefaultMask
+		}
+		a = redact.Mask(mask)
+	case "hmac":
+		if c.HMACKey == "" {
+			return redact.Rule{}, errors.New("hmac: no hmac_key")
+		}
+		a = redact.HMAC([]byte(c.HMACKey))
+	default:
+		return redact.Rule{}, fmt.Errorf("unknown action %q", c.Action)
+	}
+
+	n := 0
+	for _, set := range []bool{c.Key != "", c.Value != "", c.Fields} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return redact.Rule{}, errors.New("exactly one of key, value and fields must be set")
+	}
+	switch {
+	case c.Key != "":
+		if err := checkPattern(c.Key); err != nil {
+			return redact.Rule{}, fmt.Errorf("key: %w", err)
+		}
+		return redact.KeyRule(c.Key, a), nil
+	case c.Value != "":
+		re, err := regexp.Compile(c.Value)
+		if err != nil {
+			return redact.Rule{}, fmt.Errorf("value: %w", err)
+		}
+		return redact.ValueRule(re, a), nil
+	default:
+		return redact.FieldRule(a), nil
+	}
+}
+
+func (c *SamplingConfig) emitter(next alog.Emitter) (*sampling.Emitter, error) {
+	var opts []sampling.Option
+	if c.Interval != "" {
+		d, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("interval: %w", err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("interval: %s is not positive", d)
+		}
+		opts = append(opts, sampling.WithInterval(d))
+	}
+	switch c.Key {
+	case "", "caller":
+	case "message":
+		opts = append(opts, sampling.WithKey(sampling.MessageKey))
+	default:
+		return nil, fmt.Errorf("key: unknown key %q", c.Key)
+	}
+	if c.ExemptLevel != "" {
+		level, err := alog.ParseLevel(c.ExemptLevel)
+		if err != nil {
+			return nil, fmt.Errorf("exempt_level: %w", err)
+		}
+		opts = append(opts, sampling.WithExemptLevel(level))
+	}
+
+	var policy sampling.Policy
+	switch {
+	case c.Thereafter != 0 && c.Rate == 0:
+		policy = sampling.FirstThenEvery(c.First, c.Thereafter)
+	case c.Rate > 0 && c.Thereafter == 0 && c.First == 0:
+		if c.Burst < 1 {
+			return nil, errors.New("burst: must be at least 1 with rate")
+		}
+		policy = sampling.TokenBucket(c.Rate, c.Burst)
+	default:
+		return nil, errors.New("set either thereafter, with an optional first, or a positive rate and burst")
+	}
+	return sampling.New(next, policy, opts...), nil
+}
+
+// levelFilter returns a Filter that applies min and the overrides in vm the way
+// loggers that filter by level do. Entries without a level are accepted.
+func levelFilter(min alog.Level, vm *alog.VModule) alog.Filter {
+	return func(ctx context.Context, e *alog.Entry) bool {
+		if e.Level == alog.LevelNone {
+			return true
+		}
+		if l, ok := alog.MinLevelFromContext(ctx); ok {
+			return e.Level >= l
+		}
+		if l, ok := vm.MinLevelForFile(e.File); ok {
+			return e.Level >= l
+		}
+		return e.Level >= min
+	}
+}
+
+// tagEmitter returns an emitter that adds tags to each entry, ahead of the
+// entry's own tags, before passing it to next.
+func tagEmitter(next alog.Emitter, tags map[string]string) alog.Emitter {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	static := make([][2]string, len(keys))
+	for i, key := range keys {
+		static[i] = [2]string{key, tags[key]}
+	}
+
+	return alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		entry := *e
+		entry.Tags = make([][2]string, 0, len(static)+len(e.Tags))
+		for _, t := range static {
+			if !hasTag(e.Tags, t[0]) {
+				entry.Tags = append(entry.Tags, t)
+			}
+		}
+		entry.Tags = append(entry.Tags, e.Tags...)
+		next.Emit(ctx, &entry)
+	})
+}
+
+func hasTag(tags [][2]string, key string) bool {
+	for _, t := range tags {
+		if t[0] == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// Loader keeps a Logger configured by a file, and reconfigures it when the file
+// changes.
+type Loader struct {
+	path   string
+	o      *Options
+	em     swapEmitter
+	logger *alog.Logger
+
+	mu      sync.Mutex // serializes reloads
+	data    []byte     // the contents of the file last read
+	readErr string     // the last error reading the file, reported by Watch
+	closed  bool
+}
+
+// Load reads the Config in the file at path and returns a Loader for it. The
+// file is decoded with the function set with WithUnmarshal, or as JSON if there
+// isn't one. Unknown fields are an error in JSON, to catch misspellings. For
+// example:
+//
+//	{
+//		"level": "info",
+//		"vmodule": "billing=debug",
+//		"tags": {"service": "api"},
+//		"redact": [{"key": "password", "action": "mask"}],
+//		"sampling": {"first": 10, "thereafter": 100},
+//		"emitters": [
+//			{"format": "gkelog"},
+//			{"format": "textlog", "output": "/var/log/api-errors.log", "level": "error"}
+//		]
+//	}
+//
+// Call Watch to apply changes to the file, or Reload to apply them right away.
+// Close releases the files and background goroutines of the configuration.
+func Load(path string, opt ...Option) (*Loader, error) {
+	o := new(Options)
+	for _, option := range opt {
+		option(o)
+	}
+	o.setDefaults()
+	if o.unmarshal == nil {
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml":
+			return nil, fmt.Errorf("config: %s: decoding YAML requires WithUnmarshal", path)
+		}
+		o.unmarshal = unmarshalJSON
+	}
+
+	l := &Loader{path: path, o: o}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+	opts := append([]alog.Option{alog.WithCaller(), alog.WithFilter(l.em.filter), alog.WithEmitter(&l.em)}, o.extra...)
+	l.logger = alog.New(opts...)
+	return l, nil
+}
+
+// Logger returns the Logger configured by the file. It records the caller of
+// every entry, since any reload may need it.
+func (l *Loader) Logger() *alog.Logger {
+	return l.logger
+}
+
+// Reload reads the file and reconfigures the Logger. If the file can't be read
+// or the Config in it is invalid, it returns the error and the Logger keeps its
+// previous configuration.
+//
+// Entries being emitted while the Logger is reconfigured are finished with the
+// previous configuration before its files are closed. Errors closing them
+// don't make the reload fail; they are reported like the errors of Watch.
+func (l *Loader) Reload() error {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	l.mu.Lock()
+	old, err := l.reload(data)
+	l.mu.Unlock()
+	l.retire(old)
+	return err
+}
+
+// reload applies the Config in data, and returns the target it replaced, which
+// the caller retires once l.mu is released.
+func (l *Loader) reload(data []byte) (*target, error) {
+	if l.closed {
+		return nil, errors.New("config: Loader is closed")
+	}
+	l.data = data
+
+	var c Config
+	if err := l.o.unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", l.path, err)
+	}
+	p, err := c.build(l.o)
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", l.path, err)
+	}
+	return l.em.swap(p), nil
+}
+
+// retire closes the pipeline of old, once the entries being emitted to it are
+// done, and reports any error.
+func (l *Loader) retire(old *target) {
+	if err := old.retire(); err != nil {
+		l.reloadError(context.Background(), fmt.Errorf("config: closing the previous configuration: %w", err))
+	}
+}
+
+// Watch checks the file for changes every poll interval, and reloads it when
+// it has changed, until ctx is done. It returns ctx.Err().
+//
+// Errors are passed to the handler set with WithReloadErrorHandler, or logged
+// at LevelError with the Logger if there isn't one. Each version of the file,
+// and each error reading it, is only reported once.
+func (l *Loader) Watch(ctx context.Context) error {
+	t := time.NewTicker(l.o.pollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		old, err := l.check()
+		if err != nil {
+			l.reloadError(ctx, err)
+		}
+		l.retire(old)
+	}
+}
+
+// check reloads the file if its contents have changed since it was last read.
+func (l *Loader) check() (*target, error) {
+	data, err := os.ReadFile(l.path)
+	// Editors and config management may remove or truncate the file before
+	// writing the new version, so a missing or empty file is most likely
+	// about to change again. Wait for the next version.
+	if errors.Is(err, os.ErrNotExist) || err == nil && len(data) == 0 {
+		return nil, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, nil
+	}
+	if err != nil {
+		if err.Error() == l.readErr {
+			return nil, nil
+		}
+		l.readErr = err.Error()
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	l.readErr = ""
+	if bytes.Equal(data, l.data) {
+		return nil, nil
+	}
+	return l.reload(data)
+}
+
+func (l *Loader) reloadError(ctx context.Context, err error) {
+	if l.o.reloadError != nil {
+		l.o.reloadError(err)
+		return
+	}
+	l.logger.Print(alog.WithLevel(ctx, alog.LevelError), "reloading the log configuration: ", err)
+}
+
+// Close stops the Logger from writing entries, and releases the files and
+// background goroutines of its configuration once the entries being emitted
+// are done.
+func (l *Loader) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	old := l.em.swap(nil)
+	l.mu.Unlock()
+	return old.retire()
+}
+
+// swapEmitter is an Emitter whose pipeline can be replaced while it's in use.
+// Emit doesn't take any locks, so emitters may log to the same Logger, even
+// while the pipeline is being replaced.
+type swapEmitter struct {
+	cur atomic.Pointer[target]
+}
+
+// target is a pipeline, along with the number of entries being emitted to it,
+// so that it's only closed once they're done.
+type target struct {
+	p       *pipeline // nil once the Loader is closed
+	active  atomic.Int64
+	retired atomic.Bool
+	once    sync.Once
+	idle    chan struct{} // closed once retired and inactive
+}
+
+// Emit implements alog.Emitter.
+func (s *swapEmitter) Emit(ctx context.Context, e *alog.Entry) {
+	for {
+		t := s.cur.Load()
+		if t == nil || t.p == nil {
+			return
+		}
+		t.active.Add(1)
+		// The pipeline may have been replaced, and be about to close,
+		// before it was marked active. Use the new one then.
+		if s.cur.Load() != t {
+			t.release()
+			continue
+		}
+		t.emit(ctx, e)
+		return
+	}
+}
+
+// filter applies the level filter of the current pipeline, so that the entries
+// it rejects are dropped before they're complete. Once the Loader is closed,
+// every entry is dropped.
+func (s *swapEmitter) filter(ctx context.Context, e *alog.Entry) bool {
+	t := s.cur.Load()
+	if t == nil || t.p == nil {
+		return false
+	}
+	return t.p.filter == nil || t.p.filter(ctx, e)
+}
+
+// swap makes p the pipeline entries are emitted to, and returns the target it
+// replaced. A nil p drops entries.
+func (s *swapEmitter) swap(p *pipeline) *target {
+	return s.cur.Swap(&target{p: p, idle: make(chan struct{})})
+}
+
+func (t *target) emit(ctx context.Context, e *alog.Entry) {
+	defer t.release()
+	t.p.emitter.Emit(ctx, e)
+}
+
+func (t *target) release() {
+	if t.active.Add(-1) == 0 && t.retired.Load() {
+		t.once.Do(func() { close(t.idle) })
+	}
+}
+
+// retire waits for the entries being emitted to t, which must have been
+// replaced, and closes its pipeline.
+func (t *target) retire() error {
+	if t == nil || t.p == nil {
+		return nil
+	}
+	t.retired.Store(true)
+	if t.active.Load() == 0 {
+		t.once.Do(func() { close(t.idle) })
+	}
+	<-t.idle
+	return t.p.Close()
+}
+
+// unmarshalJSON decodes a single JSON value into v, rejecting unknown fields.
+func unmarshalJSON(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return errors.New("data after the JSON value")
+	}
+	return nil
+}
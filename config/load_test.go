@@ -0,0 +1,357 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// writeFile replaces the file name in dir with one holding data, the way
+// config management does, so Watch never sees it half written. It returns the
+// path of the file.
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+	errOut := filepath.Join(dir, "errors.log")
+	path := writeFile(t, dir, "log.json", `{
+		"level": "warning",
+		"vmodule": "load_test=info",
+		"tags": {"service": "api", "env": "test"},
+		"redact": [
+			{"key": "pass*", "action": "mask"},
+			{"value": "[0-9]{4}-[0-9]{4}", "action": "mask", "mask": "####"}
+		],
+		"emitters": [
+			{"format": "textlog", "output": "`+out+`"},
+			{"format": "textlog", "output": "`+errOut+`", "level": "error", "tags": {"env": ["test"]}}
+		]
+	}`)
+
+	l, err := Load(path, zeroTimeOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := alog.AddTags(context.Background(), "env", "prod", "password", "hunter2")
+	logger := l.Logger()
+	resolved := 0
+	lazy := alog.AddLazyTags(ctx, alog.LazyTag{Key: "lazy", Val: func() string {
+		resolved++
+		return "x"
+	}})
+	logger.Print(alog.WithLevel(lazy, alog.LevelDebug), "dropped")
+	if resolved != 0 {
+		t.Errorf("lazy tag of a dropped entry resolved %d times", resolved)
+	}
+	logger.Print(alog.WithLevel(ctx, alog.LevelInfo), "card 1234-5678")
+	logger.Print(alog.WithLevel(alog.AddTags(ctx, "env", "test"), alog.LevelError), "failed")
+	logger.Print(ctx, "no level")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Print(alog.WithLevel(ctx, alog.LevelError), "after Close")
+
+	want := `INFO      [service=api env=prod password=[REDACTED]] card ####
+ERROR     [service=api password=[REDACTED] env=test] failed
+[service=api env=prod password=[REDACTED]] no level
+`
+	if got := readFile(t, out); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	want = "ERROR     [service=api password=[REDACTED] env=test] failed\n"
+	if got := readFile(t, errOut); got != want {
+		t.Errorf("errors: got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+	config := func(level string) string {
+		return `{"level": "` + level + `", "emitters": [{"format": "textlog", "output": "` + out + `"}]}`
+	}
+	path := writeFile(t, dir, "log.json", config("error"))
+	l, err := Load(path, zeroTimeOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ctx := alog.WithLevel(context.Background(), alog.LevelInfo)
+	logger := l.Logger()
+
+	logger.Print(ctx, "1")
+	writeFile(t, dir, "log.json", config("info"))
+	if err := l.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Print(ctx, "2")
+
+	for _, bad := range []string{
+		config("loud"),
+		`{"emitters": [{"format": "textlog", "levle": "error"}]}`,
+		`{"emitters": [{"format": "xml"}]}`,
+		`{"emitters": []}`,
+		`{"emitters": [{"format": "textlog"}]} {}`,
+		`not json`,
+	} {
+		writeFile(t, dir, "log.json", bad)
+		if err := l.Reload(); err == nil {
+			t.Errorf("no error reloading %s", bad)
+		}
+	}
+	logger.Print(ctx, "3")
+
+	if got, want := readFile(t, out), "INFO      2\nINFO      3\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+	config := func(level string) string {
+		return `{"level": "` + level + `", "emitters": [{"format": "textlog", "output": "` + out + `"}]}`
+	}
+	path := writeFile(t, dir, "log.json", config("error"))
+
+	var mu sync.Mutex
+	var errs []error
+	l, err := Load(path, zeroTimeOpt, WithPollInterval(time.Millisecond), WithReloadErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Watch(ctx) }()
+
+	// waitFor polls until f returns true.
+	waitFor := func(what string, f func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !f(); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+		}
+	}
+	logger := l.Logger()
+	info := alog.WithLevel(context.Background(), alog.LevelInfo)
+
+	writeFile(t, dir, "log.json", config("loud"))
+	waitFor("the reload error", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	})
+	writeFile(t, dir, "log.json", config("info"))
+	waitFor("the reload", func() bool {
+		logger.Print(info, "hello")
+		return readFile(t, out) != ""
+	})
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Watch returned %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), `unknown level "loud"`) {
+		t.Errorf("got errors %v, want one for the invalid level", errs)
+	}
+}
+
+func TestLoadUnmarshal(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "log.yaml", `{"emitters": [{"format": "textlog"}]}`)
+	if _, err := Load(path); err == nil {
+		t.Error("no error loading YAML without WithUnmarshal")
+	}
+	l, err := Load(path, WithUnmarshal(json.Unmarshal))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+}
+
+func TestConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		config string
+		want   string
+	}{
+		{`{"vmodule": "a=", "emitters": [{"format": "textlog"}]}`, `vmodule: unknown level ""`},
+		{`{"emitters": [{"format": "gkelog", "utc": true}]}`, "emitters[0]: utc: not supported by gkelog"},
+		{`{"emitters": [{"format": "gkelog", "date_format": "2006"}]}`, "emitters[0]: date_format: not supported by gkelog"},
+		{`{"emitters": [{"format": "textlog", "files": "["}]}`, "emitters[0]: files: syntax error in pattern"},
+		{`{"emitters": [{"format": "textlog", "output": "/nonexistent/a.log"}]}`, "emitters[0]: output: open /nonexistent/a.log"},
+		{`{"redact": [{"key": "a", "action": "shred"}], "emitters": [{"format": "textlog"}]}`, `redact[0]: unknown action "shred"`},
+		{`{"redact": [{"key": "a", "value": "b", "action": "drop"}], "emitters": [{"format": "textlog"}]}`, "redact[0]: exactly one of key, value and fields must be set"},
+		{`{"redact": [{"value": "(", "action": "drop"}], "emitters": [{"format": "textlog"}]}`, "redact[0]: value: error parsing regexp"},
+		{`{"redact": [{"fields": true, "action": "hmac"}], "emitters": [{"format": "textlog"}]}`, "redact[0]: hmac: no hmac_key"},
+		{`{"sampling": {}, "emitters": [{"format": "textlog"}]}`, "sampling: set either thereafter"},
+		{`{"sampling": {"rate": 1}, "emitters": [{"format": "textlog"}]}`, "sampling: burst: must be at least 1"},
+		{`{"sampling": {"thereafter": 1, "interval": "soon"}, "emitters": [{"format": "textlog"}]}`, "sampling: interval: "},
+		{`{"sampling": {"thereafter": 1, "key": "tag"}, "emitters": [{"format": "textlog"}]}`, `sampling: key: unknown key "tag"`},
+	} {
+		var c Config
+		if err := unmarshalJSON([]byte(tc.config), &c); err != nil {
+			t.Fatal(err)
+		}
+		o := new(Options)
+		o.setDefaults()
+		_, err := c.build(o)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Errorf("%s: got error %v, want %s", tc.config, err, tc.want)
+		}
+	}
+}
+
+func TestConfigSampling(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "app.log")
+	path := writeFile(t, dir, "log.json", `{
+		"sampling": {"first": 1, "thereafter": 100, "interval": "1h", "key": "message"},
+		"emitters": [{"format": "textlog", "output": "`+out+`"}]
+	}`)
+	l, err := Load(path, zeroTimeOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		l.Logger().Print(context.Background(), "again")
+	}
+	// Closing writes the summary for the window.
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "again\n[suppressed=2] suppressed 2 entries like: again\n"
+	if got := readFile(t, out); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestReloadCloseError(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "log.json", `{"emitters": [{"format": "textlog", "output": "`+filepath.Join(dir, "app.log")+`"}]}`)
+	var errs []error
+	l, err := Load(path, WithReloadErrorHandler(func(err error) { errs = append(errs, err) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Replace the pipeline with one that fails to close.
+	l.em.swap(&pipeline{
+		emitter: alog.EmitterFunc(func(context.Context, *alog.Entry) {}),
+		closers: []io.Closer{closerFunc(func() error { return os.ErrClosed })},
+	}).retire()
+	if err := l.Reload(); err != nil {
+		t.Errorf("Reload failed: %v", err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrClosed) {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+func TestWatchReadError(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "log.json", `{"emitters": [{"format": "textlog", "output": "`+filepath.Join(dir, "app.log")+`"}]}`)
+	var mu sync.Mutex
+	var errs []error
+	l, err := Load(path, WithPollInterval(time.Millisecond), WithReloadErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Reading a directory fails the same way every time.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	l.Watch(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one", errs)
+	}
+}
+
+// TestSwapReentrant checks that an emitter can log to the same Logger while
+// its pipeline is being replaced.
+func TestSwapReentrant(t *testing.T) {
+	var s swapEmitter
+	l := alog.New(alog.WithEmitter(&s))
+	ctx := context.Background()
+
+	var got []string
+	started, proceed := make(chan struct{}), make(chan struct{})
+	var finished bool
+	s.swap(&pipeline{emitter: alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		close(started)
+		<-proceed
+		l.Print(ctx, "nested")
+		finished = true
+	})})
+	go l.Print(ctx, "outer")
+	<-started
+
+	old := s.swap(&pipeline{emitter: alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		got = append(got, e.Msg)
+	})})
+	retired := make(chan error)
+	go func() { retired <- old.retire() }()
+	close(proceed)
+	if err := <-retired; err != nil {
+		t.Fatal(err)
+	}
+	if !finished || len(got) != 1 || got[0] != "nested" {
+		t.Errorf("finished %v, got %q", finished, got)
+	}
+}
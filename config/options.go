@@ -0,0 +1,109 @@
+package config
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// DefaultPrefix is the prefix of the environment variables read by FromEnv if
+// WithPrefix is not specified.
+const DefaultPrefix = "ALOG_"
+
+// DefaultPollInterval is how often Loader.Watch checks the file for changes if
+// WithPollInterval is not specified.
+const DefaultPollInterval = 2 * time.Second
+
+// Options holds option values.
+type Options struct {
+	prefix string
+	lookup func(key string) (string, bool)
+	stdout io.Writer
+	stderr io.Writer
+	extra  []alog.Option
+
+	unmarshal    func(data []byte, v interface{}) error
+	pollInterval time.Duration
+	reloadError  func(error)
+}
+
+// Option sets an option for FromEnv or Load.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithPrefix sets the prefix of the environment variable names.
+//
+// If this option is not specified, DefaultPrefix is used.
+func WithPrefix(prefix string) Option {
+	return func(o *Options) { o.prefix = prefix }
+}
+
+// WithLookup sets the function used to look up environment variables, for
+// tests and for configuration taken from somewhere else.
+//
+// If this option is not specified, os.LookupEnv is used.
+func WithLookup(lookup func(key string) (string, bool)) Option {
+	return func(o *Options) { o.lookup = lookup }
+}
+
+// WithStdio sets the writers used for the "stdout" and "stderr" outputs.
+//
+// If this option is not specified, os.Stdout and os.Stderr are used.
+func WithStdio(stdout, stderr io.Writer) Option {
+	return func(o *Options) {
+		o.stdout = stdout
+		o.stderr = stderr
+	}
+}
+
+// WithLoggerOptions adds options for the Logger, applied after the ones
+// derived from the environment. The level of the configuration is applied with
+// alog.WithFilter, so passing that option replaces it.
+func WithLoggerOptions(opt ...alog.Option) Option {
+	return func(o *Options) { o.extra = append(o.extra, opt...) }
+}
+
+// WithUnmarshal sets the function Load uses to decode the file, such as
+// yaml.Unmarshal from gopkg.in/yaml.v3 or sigs.k8s.io/yaml for YAML files.
+//
+// If this option is not specified, files are decoded as JSON.
+func WithUnmarshal(unmarshal func(data []byte, v interface{}) error) Option {
+	return func(o *Options) { o.unmarshal = unmarshal }
+}
+
+// WithPollInterval sets how often Loader.Watch checks the file for changes.
+//
+// If this option is not specified, DefaultPollInterval is used.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *Options) { o.pollInterval = d }
+}
+
+// WithReloadErrorHandler sets the function that Loader.Watch passes the errors
+// from reloading the file to, and that Loader.Watch and Loader.Reload pass the
+// errors closing a replaced configuration to.
+//
+// If this option is not specified, the errors are logged with the Logger.
+func WithReloadErrorHandler(h func(error)) Option {
+	return func(o *Options) { o.reloadError = h }
+}
+
+func (o *Options) setDefaults() {
+	if o.prefix == "" {
+		o.prefix = DefaultPrefix
+	}
+	if o.lookup == nil {
+		o.lookup = os.LookupEnv
+	}
+	if o.stdout == nil {
+		o.stdout = os.Stdout
+	}
+	if o.stderr == nil {
+		o.stderr = os.Stderr
+	}
+	if o.pollInterval <= 0 {
+		o.pollInterval = DefaultPollInterval
+	}
+}
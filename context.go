@@ -19,40 +19,145 @@ type STag struct {
 
 // AddTags adds paired strings to the set of tags in the Context.
 //
+// Tags in the Context have unique keys: adding a tag with a key that is already
+// present removes the old tag, and the new one is placed after the others. If
+// a key appears more than once in pairs, the last value is used.
+//
 // Any unpaired strings are ignored.
 func AddTags(ctx context.Context, pairs ...string) context.Context {
-	old := tagsFromContext(ctx)
-	new := make([][2]string, len(old)+(len(pairs)/2))
-	copy(new, old)
-	for o := range new[len(old):] {
-		new[len(old)+o][0] = pairs[o*2]
-		new[len(old)+o][1] = pairs[o*2+1]
+	return context.WithValue(ctx, stringCtxKey, tagNodeFromContext(ctx).with(opAdd, pairsToTags(pairs), nil))
+}
+
+// ReplaceTags sets the values of tags in the Context from paired strings. Tags
+// that are already present keep their position, and the others are added after
+// the existing tags.
+//
+// Any unpaired strings are ignored.
+func ReplaceTags(ctx context.Context, pairs ...string) context.Context {
+	return context.WithValue(ctx, stringCtxKey, tagNodeFromContext(ctx).with(opReplace, pairsToTags(pairs), nil))
+}
+
+// RemoveTags removes the tags, structured tags and lazy tags with the given
+// keys from the Context.
+func RemoveTags(ctx context.Context, keys ...string) context.Context {
+	if n := tagNodeFromContext(ctx); n != nil {
+		ctx = context.WithValue(ctx, stringCtxKey, n.with(opRemove, nil, keys))
+	}
+	if n := sTagNodeFromContext(ctx); n != nil {
+		ctx = context.WithValue(ctx, structuredCtxKey, n.with(opRemove, nil, keys))
 	}
-	return context.WithValue(ctx, stringCtxKey, new)
+	if n := lazyTagNodeFromContext(ctx); n != nil {
+		ctx = context.WithValue(ctx, lazyCtxKey, n.with(opRemove, nil, keys))
+	}
+	return ctx
 }
 
-// tagsFromContext wraps the type assertion coming out of a Context.
-func tagsFromContext(ctx context.Context) [][2]string {
-	if t, ok := ctx.Value(stringCtxKey).([][2]string); ok {
-		return t
+// WithoutTags returns a copy of ctx without any tags, structured tags or lazy
+// tags, to start a clean tag scope. The other values of ctx are kept.
+func WithoutTags(ctx context.Context) context.Context {
+	if tagNodeFromContext(ctx) != nil {
+		ctx = context.WithValue(ctx, stringCtxKey, (*tagNode[[2]string])(nil))
 	}
-	return nil
+	if sTagNodeFromContext(ctx) != nil {
+		ctx = context.WithValue(ctx, structuredCtxKey, (*tagNode[STag])(nil))
+	}
+	if lazyTagNodeFromContext(ctx) != nil {
+		ctx = context.WithValue(ctx, lazyCtxKey, (*tagNode[LazyTag])(nil))
+	}
+	return ctx
+}
+
+// TagValue returns the value of the tag with key in the Context, and whether
+// there is one. Lazy tags are not included.
+func TagValue(ctx context.Context, key string) (string, bool) {
+	tags := tagsFromContext(ctx)
+	if i := indexKey(tags, key, tagKey); i >= 0 {
+		return tags[i][1], true
+	}
+	return "", false
+}
+
+// tagsFromContext returns the tags in the Context.
+func tagsFromContext(ctx context.Context) [][2]string {
+	return tagNodeFromContext(ctx).list(tagKey)
+}
+
+// tagNodeFromContext wraps the type assertion coming out of a Context.
+func tagNodeFromContext(ctx context.Context) *tagNode[[2]string] {
+	n, _ := ctx.Value(stringCtxKey).(*tagNode[[2]string])
+	return n
 }
 
 // AddStructuredTags adds tag structures to the Context.
+//
+// Like the tags added with AddTags, structured tags have unique keys, and
+// adding one with a key that is already present replaces the old one.
 func AddStructuredTags(ctx context.Context, tags ...STag) context.Context {
-	oldTags := sTagsFromContext(ctx)
-
-	newTags := append(oldTags[:len(oldTags):len(oldTags)], tags...)
+	tags = uniqueTags(tags, sTagKey)
+	return context.WithValue(ctx, structuredCtxKey, sTagNodeFromContext(ctx).with(opAdd, tags, nil))
+}
 
-	return context.WithValue(ctx, structuredCtxKey, newTags)
+// sTagsFromContext returns the structured tags in the Context.
+func sTagsFromContext(ctx context.Context) []STag {
+	return sTagNodeFromContext(ctx).list(sTagKey)
 }
 
-// sTagsFromContext wraps the type assertion for structured tags coming out
+// sTagNodeFromContext wraps the type assertion for structured tags coming out
 // of the context.
-func sTagsFromContext(ctx context.Context) []STag {
-	if tags, ok := ctx.Value(structuredCtxKey).([]STag); ok {
-		return tags
+func sTagNodeFromContext(ctx context.Context) *tagNode[STag] {
+	n, _ := ctx.Value(structuredCtxKey).(*tagNode[STag])
+	return n
+}
+
+// pairsToTags turns paired strings into tags with unique keys, keeping the
+// last value of each key.
+func pairsToTags(pairs []string) [][2]string {
+	tags := make([][2]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		t := [2]string{pairs[i], pairs[i+1]}
+		if j := indexKey(tags, t[0], tagKey); j >= 0 {
+			tags = append(tags[:j], tags[j+1:]...)
+		}
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// uniqueTags returns a copy of tags without the tags that have the same key as
+// a later one.
+func uniqueTags[T any](tags []T, key func(T) string) []T {
+	out := make([]T, 0, len(tags))
+	for i, t := range tags {
+		if indexKey(tags[i+1:], key(t), key) < 0 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// mergeTags returns the tags of base whose keys are not in over, followed by
+// over. Both must have unique keys.
+func mergeTags[T any](base, over []T, key func(T) string) []T {
+	if len(over) == 0 {
+		return base
+	}
+	if len(base) == 0 {
+		return over
+	}
+	merged := make([]T, 0, len(base)+len(over))
+	for _, t := range base {
+		if indexKey(over, key(t), key) < 0 {
+			merged = append(merged, t)
+		}
+	}
+	return append(merged, over...)
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
 	}
-	return ([]STag)(nil)
+	return false
 }
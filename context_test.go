@@ -0,0 +1,113 @@
+package alog
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestTagListCache(t *testing.T) {
+	t.Parallel()
+	parent := AddTags(benchContext(20), "key3", "moved")
+	parent = RemoveTags(parent, "key4")
+	children := []context.Context{
+		AddTags(parent, "a", "1"),
+		ReplaceTags(parent, "key0", "replaced"),
+		parent,
+		RemoveTags(parent, "key3"),
+	}
+
+	want := make([]string, len(children))
+	for i, ctx := range children {
+		want[i] = fmt.Sprint(tagsFromContext(ctx))
+	}
+	if got := fmt.Sprint(tagsFromContext(parent)); got != want[2] {
+		t.Errorf("parent changed after flattening children: got %s, want %s", got, want[2])
+	}
+	base := make([][2]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		if i != 3 && i != 4 {
+			base = append(base, [2]string{benchKeys[i], "value"})
+		}
+	}
+	if got, wantBase := want[2], fmt.Sprint(append(base, [2]string{"key3", "moved"})); got != wantBase {
+		t.Errorf("got %s, want %s", got, wantBase)
+	}
+
+	// Flatten fresh copies of the same contexts concurrently, for the race
+	// detector.
+	var wg sync.WaitGroup
+	parent = AddTags(benchContext(20), "key3", "moved")
+	parent = RemoveTags(parent, "key4")
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := parent
+			switch i % 4 {
+			case 0:
+				ctx = AddTags(parent, "a", "1")
+			case 1:
+				ctx = ReplaceTags(parent, "key0", "replaced")
+			case 3:
+				ctx = RemoveTags(parent, "key3")
+			}
+			if got := fmt.Sprint(tagsFromContext(ctx)); got != want[i%4] {
+				t.Errorf("got %s, want %s", got, want[i%4])
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+var benchKeys = func() []string {
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	return keys
+}()
+
+// benchContext returns a Context with depth tags, each added by its own call to
+// AddTags the way a stack of middleware would.
+func benchContext(depth int) context.Context {
+	ctx := context.Background()
+	for i := 0; i < depth; i++ {
+		ctx = AddTags(ctx, benchKeys[i], "value")
+	}
+	return ctx
+}
+
+func BenchmarkAddTags(b *testing.B) {
+	for _, depth := range []int{1, 8, 64} {
+		b.Run(strconv.Itoa(depth), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchContext(depth)
+			}
+		})
+	}
+}
+
+func BenchmarkOutput(b *testing.B) {
+	l := New(WithEmitter(EmitterFunc(func(ctx context.Context, e *Entry) {
+		e.Resolve()
+	})))
+	for _, depth := range []int{1, 8, 64} {
+		ctx := benchContext(depth)
+		b.Run(strconv.Itoa(depth), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.Output(ctx, 1, "test")
+			}
+		})
+		b.Run(strconv.Itoa(depth)+"/child", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.Output(AddTags(ctx, "child", "value"), 1, "test")
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Package async provides an alog.Emitter that hands entries off to a
+// background goroutine, so slow writers don't stall the goroutines doing the
+// logging.
+package async
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// ErrClosed is returned by Close if the Emitter has already been closed.
+var ErrClosed = errors.New("async: emitter closed")
+
+type item struct {
+	ctx context.Context
+	e   alog.Entry
+}
+
+// Emitter queues entries and emits them through the wrapped emitter from a
+// single background goroutine, in the order they were queued.
+//
+// Each queued entry is a copy, and its context is captured at the time of the
+// Emit call, so the wrapped emitter renders it the same way it would have
+// synchronously.
+//
+// Close must be called to release the background goroutine and to make sure
+// the queued entries are written.
+type Emitter struct {
+	next alog.Emitter
+	o    Options
+
+	queue chan item
+
+	// closeMu is held for reading while entries are queued, and for
+	// writing while closing the queue.
+	closeMu sync.RWMutex
+	closed  bool
+
+	// queued counts the entries that have been queued, or are being
+	// queued. It's incremented before an entry is sent on queue, so it
+	// includes every entry ahead of it in the queue.
+	queued  atomic.Uint64
+	dropped atomic.Uint64
+
+	// mu protects finished, the count of queued entries that have been
+	// emitted or dropped. cond is signalled when it changes.
+	mu       sync.Mutex
+	cond     sync.Cond
+	finished uint64
+
+	stopped chan struct{}
+}
+
+// New returns an Emitter that emits entries through next from a background
+// goroutine.
+func New(next alog.Emitter, opt ...Option) *Emitter {
+	o := Options{
+		queueSize: DefaultQueueSize,
+		snapshot:  snapshot,
+	}
+	for _, option := range opt {
+		option(&o)
+	}
+	if o.queueSize < 1 {
+		o.queueSize = 1
+	}
+
+	a := &Emitter{
+		next:    next,
+		o:       o,
+		queue:   make(chan item, o.queueSize),
+		stopped: make(chan struct{}),
+	}
+	a.cond.L = &a.mu
+	go a.run()
+	return a
+}
+
+// Emit implements alog.Emitter. Entries emitted after Close are dropped.
+func (a *Emitter) Emit(ctx context.Context, e *alog.Entry) {
+	a.closeMu.RLock()
+	defer a.closeMu.RUnlock()
+	if a.closed {
+		a.dropped.Add(1)
+		return
+	}
+
+	it := item{ctx: a.o.snapshot(ctx), e: *e}
+	a.queued.Add(1)
+	switch a.o.policy {
+	case DropNewest:
+		select {
+		case a.queue <- it:
+		default:
+			a.dropped.Add(1)
+			a.unqueue()
+		}
+	case DropOldest:
+		for queued := false; !queued; {
+			select {
+			case a.queue <- it:
+				queued = true
+			default:
+				select {
+				case <-a.queue:
+					a.dropped.Add(1)
+					a.finish()
+				default:
+				}
+			}
+		}
+	default:
+		a.queue <- it
+	}
+}
+
+// Dropped returns the number of entries that have been discarded, either by the
+// overflow policy or because they were emitted after Close.
+func (a *Emitter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Flush waits until all the entries queued before the call have been emitted,
+// or until ctx is done.
+func (a *Emitter) Flush(ctx context.Context) error {
+	target := a.queued.Load()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stop := context.AfterFunc(ctx, func() {
+		a.mu.Lock()
+		a.cond.Broadcast()
+		a.mu.Unlock()
+	})
+	defer stop()
+	// Entries that were being queued when target was read may have been
+	// dropped since.
+	for a.finished < min(target, a.queued.Load()) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		a.cond.Wait()
+	}
+	return nil
+}
+
+// Close stops accepting entries, and waits for the ones already queued to be
+// emitted.
+func (a *Emitter) Close() error {
+	a.closeMu.Lock()
+	if a.closed {
+		a.closeMu.Unlock()
+		return ErrClosed
+	}
+	a.closed = true
+	close(a.queue)
+	a.closeMu.Unlock()
+
+	<-a.stopped
+	return nil
+}
+
+func (a *Emitter) run() {
+	defer close(a.stopped)
+	for it := range a.queue {
+		a.next.Emit(it.ctx, &it.e)
+		a.finish()
+	}
+}
+
+// unqueue takes back the count of an entry that was dropped before it was
+// queued.
+func (a *Emitter) unqueue() {
+	a.mu.Lock()
+	a.queued.Add(^uint64(0))
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
+
+// finish records that a queued entry has been emitted or dropped.
+func (a *Emitter) finish() {
+	a.mu.Lock()
+	a.finished++
+	a.cond.Broadcast()
+	a.mu.Unlock()
+}
@@ -0,0 +1,213 @@
+package async
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+)
+
+var zeroTimeOpt = alog.OverrideTimestamp(func() time.Time { return time.Time{} })
+
+// gatedEmitter records messages, but only once the gate is opened.
+type gatedEmitter struct {
+	gate chan struct{}
+
+	mu   sync.Mutex
+	msgs []string
+}
+
+func newGatedEmitter() *gatedEmitter {
+	return &gatedEmitter{gate: make(chan struct{})}
+}
+
+func (g *gatedEmitter) Emit(ctx context.Context, e *alog.Entry) {
+	<-g.gate
+	g.mu.Lock()
+	g.msgs = append(g.msgs, e.Msg)
+	g.mu.Unlock()
+}
+
+func (g *gatedEmitter) String() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return fmt.Sprint(g.msgs)
+}
+
+func TestContextSnapshot(t *testing.T) {
+	b := &bytes.Buffer{}
+	a := New(gkelog.Emitter(gkelog.WithWriter(b)))
+	l := alog.New(alog.WithEmitter(a), zeroTimeOpt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gkelog.LogError(gkelog.WithTrace(ctx, "t1"), l, "test")
+	cancel()
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"0001-01-01T00:00:00Z", "severity":"ERROR", "logging.googleapis.com/trace":"t1", "logging.googleapis.com/trace_sampled":true, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRequestSnapshot(t *testing.T) {
+	b := &bytes.Buffer{}
+	g := newGatedEmitter()
+	next := gkelog.Emitter(gkelog.WithWriter(b))
+	a := New(alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		g.Emit(ctx, e)
+		next.Emit(ctx, e)
+	}))
+	l := alog.New(alog.WithEmitter(a), zeroTimeOpt)
+
+	req := httptest.NewRequest(http.MethodGet, "/test?q=1", nil)
+	req.Header.Set("Dnt", "1")
+	l.Print(gkelog.WithRequest(context.Background(), req), "test")
+
+	// The handler goes on with the request while the entry is written.
+	close(g.gate)
+	req.Header.Set("Dnt", "0")
+	req.Header.Set("X-Added", "1")
+	req.URL.RawQuery = "q=2"
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"0001-01-01T00:00:00Z", "httpRequest":{"requestMethod":"GET", "requestUrl":"/test?q=1"}, "httpHeaders":{"Dnt":["1"]}, "httpQuery":{"q":["1"]}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestOverflowPolicy(t *testing.T) {
+	for _, tbl := range []struct {
+		policy OverflowPolicy
+		want   string
+	}{
+		{policy: DropNewest, want: "[0 1 2]"},
+		{policy: DropOldest, want: "[0 4 5]"},
+	} {
+		tbl := tbl
+		t.Run(fmt.Sprint(tbl.policy), func(t *testing.T) {
+			g := newGatedEmitter()
+			a := New(g, WithQueueSize(2), WithOverflowPolicy(tbl.policy))
+			l := alog.New(alog.WithEmitter(a))
+
+			ctx := context.Background()
+			l.Print(ctx, "0")
+			// Wait for the first entry to be picked up by the
+			// background goroutine, so the queue is empty.
+			for len(a.queue) != 0 {
+				time.Sleep(time.Millisecond)
+			}
+			for i := 1; i < 6; i++ {
+				l.Print(ctx, i)
+			}
+			close(g.gate)
+
+			if err := a.Flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if got := g.String(); got != tbl.want {
+				t.Errorf("got: %s, want: %s", got, tbl.want)
+			}
+			if got := a.Dropped(); got != 3 {
+				t.Errorf("dropped: got %d, want 3", got)
+			}
+			a.Close()
+		})
+	}
+}
+
+func TestFlush(t *testing.T) {
+	g := newGatedEmitter()
+	a := New(g)
+	defer a.Close()
+	l := alog.New(alog.WithEmitter(a))
+
+	l.Print(context.Background(), "test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := a.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got: %v, want: %v", err, context.DeadlineExceeded)
+	}
+
+	close(g.gate)
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.String(), "[test]"; got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestFlushConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	emitted := map[string]bool{}
+	a := New(alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		mu.Lock()
+		emitted[e.Msg] = true
+		mu.Unlock()
+	}), WithQueueSize(4))
+	defer a.Close()
+	l := alog.New(alog.WithEmitter(a))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				msg := strconv.Itoa(i) + "-" + strconv.Itoa(j)
+				l.Print(context.Background(), msg)
+				if err := a.Flush(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				ok := emitted[msg]
+				mu.Unlock()
+				if !ok {
+					t.Errorf("Flush returned before %s was emitted", msg)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestClose(t *testing.T) {
+	g := newGatedEmitter()
+	close(g.gate)
+	a := New(g)
+	l := alog.New(alog.WithEmitter(a))
+
+	ctx := context.Background()
+	l.Print(ctx, "before")
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l.Print(ctx, "after")
+
+	if got, want := g.String(), "[before]"; got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+	if got := a.Dropped(); got != 1 {
+		t.Errorf("dropped: got %d, want 1", got)
+	}
+	if err := a.Close(); err != ErrClosed {
+		t.Errorf("got: %v, want: %v", err, ErrClosed)
+	}
+}
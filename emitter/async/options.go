@@ -0,0 +1,74 @@
+package async
+
+import (
+	"context"
+
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+)
+
+// DefaultQueueSize is the number of entries buffered if WithQueueSize is not
+// specified.
+const DefaultQueueSize = 1024
+
+// OverflowPolicy decides what happens to an entry emitted while the queue is
+// full.
+type OverflowPolicy uint8
+
+const (
+	// Block makes Emit wait until there is room in the queue.
+	Block OverflowPolicy = iota
+	// DropNewest discards the entry being emitted.
+	DropNewest
+	// DropOldest discards the oldest entry in the queue to make room for
+	// the entry being emitted.
+	DropOldest
+)
+
+// Options holds option values.
+type Options struct {
+	queueSize int
+	policy    OverflowPolicy
+	snapshot  func(context.Context) context.Context
+}
+
+// Option sets an option for the emitter.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithQueueSize sets the number of entries that can wait to be written.
+//
+// If this option is not specified, DefaultQueueSize is used.
+func WithQueueSize(n int) Option {
+	return func(o *Options) { o.queueSize = n }
+}
+
+// WithOverflowPolicy sets what happens to entries emitted while the queue is
+// full.
+//
+// If this option is not specified, Block is used.
+func WithOverflowPolicy(p OverflowPolicy) Option {
+	return func(o *Options) { o.policy = p }
+}
+
+// WithSnapshot sets the function used to capture the context of an entry
+// before it is queued. It's called synchronously from Emit, and the context it
+// returns is the one passed to the wrapped emitter.
+//
+// The default snapshot detaches the context from the cancellation of its
+// parent, and replaces the request set with gkelog.WithRequest, if any, with a
+// clone, since handlers may modify the request after logging. Use this option
+// if the wrapped emitter reads other values that may be modified after Emit
+// returns.
+func WithSnapshot(f func(context.Context) context.Context) Option {
+	return func(o *Options) { o.snapshot = f }
+}
+
+// snapshot is the default snapshot function.
+func snapshot(ctx context.Context) context.Context {
+	ctx = context.WithoutCancel(ctx)
+	if req := gkelog.RequestFromContext(ctx); req != nil {
+		ctx = gkelog.ReplaceRequest(ctx, req.Clone(req.Context()))
+	}
+	return ctx
+}
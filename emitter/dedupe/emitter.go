@@ -0,0 +1,201 @@
+// Package dedupe provides an alog.Emitter that suppresses repeated entries and
+// periodically reports how many times they were repeated, like syslog's "last
+// message repeated" lines.
+package dedupe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/internal"
+)
+
+// Keys of the structured tags added to rollup entries.
+const (
+	RepeatedKey  = "repeated"
+	FirstSeenKey = "first_seen"
+	LastSeenKey  = "last_seen"
+)
+
+// ErrClosed is returned by Close if the Emitter has already been closed.
+var ErrClosed = errors.New("dedupe: emitter closed")
+
+type repeat struct {
+	count       uint64
+	first, last time.Time
+
+	// ctx and e are the latest repeat, used as the template for the rollup
+	// entry.
+	ctx context.Context
+	e   alog.Entry
+}
+
+// Emitter passes entries on to another emitter, dropping the ones identical to
+// an entry already emitted in the current window. Entries are identical if
+// they have the same message, level, tags and caller; structured tags are not
+// compared.
+//
+// At the end of each window, a rollup entry is emitted for every entry that
+// was repeated. It's a copy of the latest repeat with the message replaced,
+// and the RepeatedKey, FirstSeenKey and LastSeenKey structured tags added.
+//
+// Close must be called to stop the background goroutine that ends the
+// windows.
+type Emitter struct {
+	next alog.Emitter
+	o    Options
+
+	mu   sync.Mutex
+	seen map[string]*repeat
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+// New returns an Emitter that emits entries through next, suppressing
+// repeats.
+func New(next alog.Emitter, opt ...Option) *Emitter {
+	o := Options{
+		window: DefaultWindow,
+	}
+	for _, option := range opt {
+		option(&o)
+	}
+	if o.window <= 0 {
+		o.window = DefaultWindow
+	}
+
+	d := &Emitter{
+		next:    next,
+		o:       o,
+		seen:    make(map[string]*repeat),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// writeKey writes the key that identifies identical entries to b. Every field
+// is prefixed with its length, so that no characters in the tags or message can
+// make two different entries produce the same key.
+func writeKey(b *bytes.Buffer, e *alog.Entry) {
+	writeField(b, e.Level.String())
+	writeField(b, e.File)
+	internal.Itoa(b, uint(e.Line))
+	b.WriteByte(':')
+	for _, t := range e.Tags {
+		writeField(b, t[0])
+		writeField(b, t[1])
+	}
+	writeField(b, e.Msg)
+}
+
+func writeField(b *bytes.Buffer, s string) {
+	internal.Itoa(b, uint(len(s)))
+	b.WriteByte(':')
+	b.WriteString(s)
+}
+
+// Emit implements alog.Emitter.
+func (d *Emitter) Emit(ctx context.Context, e *alog.Entry) {
+	// Looking the key up as string(b.Bytes()) doesn't allocate, so only
+	// the keys of new entries are copied out of the pooled buffer.
+	b := internal.GetBuffer()
+	defer internal.PutBuffer(b)
+	writeKey(b, e)
+
+	d.mu.Lock()
+	r, ok := d.seen[string(b.Bytes())]
+	if !ok {
+		d.seen[b.String()] = &repeat{}
+		d.mu.Unlock()
+		d.next.Emit(ctx, e)
+		return
+	}
+	if r.count == 0 {
+		r.first = e.Time
+	}
+	r.count++
+	r.last = e.Time
+	r.ctx = context.WithoutCancel(ctx)
+	r.e = *e
+	d.mu.Unlock()
+}
+
+// Close emits the rollups for the current window and stops the background
+// goroutine.
+func (d *Emitter) Close() error {
+	err := ErrClosed
+	d.closeOnce.Do(func() {
+		close(d.stop)
+		<-d.stopped
+		d.endWindow()
+		err = nil
+	})
+	return err
+}
+
+func (d *Emitter) run() {
+	defer close(d.stopped)
+	t := time.NewTicker(d.o.window)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			d.endWindow()
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// endWindow forgets every entry seen in the window, and emits the rollups
+// for the repeated ones.
+func (d *Emitter) endWindow() {
+	d.mu.Lock()
+	seen := d.seen
+	d.seen = make(map[string]*repeat, len(seen))
+	d.mu.Unlock()
+
+	for _, r := range seen {
+		if r.count == 0 {
+			continue
+		}
+		e := r.e
+		e.Msg = fmt.Sprintf("last message repeated %d times: %s", r.count, e.Msg)
+		e.STags = replaceSTags(e.STags,
+			alog.STag{Key: RepeatedKey, Val: r.count},
+			alog.STag{Key: FirstSeenKey, Val: r.first},
+			alog.STag{Key: LastSeenKey, Val: r.last},
+		)
+		d.next.Emit(r.ctx, &e)
+	}
+}
+
+// replaceSTags returns a copy of tags with add appended, in place of the
+// structured tags with the same keys, since keys must be unique.
+func replaceSTags(tags []alog.STag, add ...alog.STag) []alog.STag {
+	out := make([]alog.STag, 0, len(tags)+len(add))
+	for _, t := range tags {
+		if !hasSTag(add, t.Key) {
+			out = append(out, t)
+		}
+	}
+	return append(out, add...)
+}
+
+func hasSTag(tags []alog.STag, key string) bool {
+	for _, t := range tags {
+		if t.Key == key {
+			return true
+		}
+	}
+	return false
+}
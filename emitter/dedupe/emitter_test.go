@@ -0,0 +1,135 @@
+package dedupe
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/jsonlog"
+)
+
+func TestEmitter(t *testing.T) {
+	b := &bytes.Buffer{}
+	d := New(jsonlog.Emitter(b, jsonlog.WithDateFormat("")), WithWindow(time.Hour))
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := alog.New(alog.WithEmitter(d), alog.OverrideTimestamp(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}))
+
+	ctx := alog.AddTags(context.Background(), "dep", "db")
+	for i := 0; i < 4; i++ {
+		l.Print(ctx, "connection refused")
+	}
+	l.Print(alog.AddTags(ctx, "dep", "cache"), "connection refused")
+	d.Close()
+
+	want := `{"tags":{"dep":"db"}, "message":"connection refused"}` + "\n" +
+		`{"tags":{"dep":"cache"}, "message":"connection refused"}` + "\n" +
+		`{"tags":{"dep":"db"}, "sTags":{"repeated":3, "first_seen":"2020-01-02T03:04:07Z", "last_seen":"2020-01-02T03:04:09Z"}, "message":"last message repeated 3 times: connection refused"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	b := &bytes.Buffer{}
+	d := New(jsonlog.Emitter(b, jsonlog.WithDateFormat("")), WithWindow(time.Hour))
+	defer d.Close()
+	l := alog.New(alog.WithEmitter(d))
+
+	ctx := context.Background()
+	l.Print(ctx, "a")
+	d.endWindow()
+	l.Print(ctx, "a")
+	d.endWindow()
+
+	want := `{"message":"a"}` + "\n" + `{"message":"a"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestClose(t *testing.T) {
+	for _, w := range []time.Duration{0, -time.Second} {
+		d := New(alog.EmitterFunc(func(context.Context, *alog.Entry) {}), WithWindow(w))
+		if d.o.window != DefaultWindow {
+			t.Errorf("WithWindow(%v): window %v, want %v", w, d.o.window, DefaultWindow)
+		}
+		if err := d.Close(); err != nil {
+			t.Errorf("got: %v, want: nil", err)
+		}
+		if err := d.Close(); err != ErrClosed {
+			t.Errorf("got: %v, want: %v", err, ErrClosed)
+		}
+	}
+}
+
+func TestKeyCollision(t *testing.T) {
+	key := func(e *alog.Entry) string {
+		b := &bytes.Buffer{}
+		writeKey(b, e)
+		return b.String()
+	}
+	for _, tags := range [][2][][2]string{
+		{{{"a=b", "c"}}, {{"a", "b=c"}}},
+		{{{"a", "b\x00c=d"}}, {{"a", "b"}, {"c", "d"}}},
+	} {
+		e1 := &alog.Entry{Tags: tags[0], Msg: "m"}
+		e2 := &alog.Entry{Tags: tags[1], Msg: "m"}
+		if key(e1) == key(e2) {
+			t.Errorf("tags %q and %q have the same key", tags[0], tags[1])
+		}
+	}
+}
+
+func BenchmarkRepeated(b *testing.B) {
+	d := New(alog.EmitterFunc(func(context.Context, *alog.Entry) {}), WithWindow(time.Hour))
+	defer d.Close()
+	ctx := context.Background()
+	e := &alog.Entry{Level: alog.LevelError, Tags: [][2]string{{"user", "alice"}}, Msg: "denied"}
+	d.Emit(ctx, e)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Emit(ctx, e)
+	}
+}
+
+func TestLazyTags(t *testing.T) {
+	b := &bytes.Buffer{}
+	d := New(jsonlog.Emitter(b, jsonlog.WithDateFormat("")), WithWindow(time.Hour))
+	l := alog.New(alog.WithEmitter(d))
+
+	for _, user := range []string{"alice", "bob"} {
+		user := user
+		l.Print(alog.AddLazyTags(context.Background(), alog.LazyTag{Key: "user", Val: func() string { return user }}), "denied")
+	}
+	d.Close()
+
+	want := `{"tags":{"user":"alice"}, "message":"denied"}` + "\n" +
+		`{"tags":{"user":"bob"}, "message":"denied"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRollupKeys(t *testing.T) {
+	b := &bytes.Buffer{}
+	d := New(jsonlog.Emitter(b, jsonlog.WithDateFormat("")), WithWindow(time.Hour))
+	l := alog.New(alog.WithEmitter(d), alog.OverrideTimestamp(func() time.Time { return time.Time{} }))
+
+	ctx := alog.AddStructuredTags(context.Background(), alog.STag{Key: RepeatedKey, Val: "no"}, alog.STag{Key: "n", Val: 1})
+	l.Print(ctx, "x")
+	l.Print(ctx, "x")
+	d.Close()
+
+	want := `{"sTags":{"repeated":"no", "n":1}, "message":"x"}` + "\n" +
+		`{"sTags":{"n":1, "repeated":1, "first_seen":"0001-01-01T00:00:00Z", "last_seen":"0001-01-01T00:00:00Z"}, "message":"last message repeated 1 times: x"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,26 @@
+package dedupe
+
+import "time"
+
+// DefaultWindow is how long duplicates are suppressed if WithWindow is not
+// specified.
+const DefaultWindow = 30 * time.Second
+
+// Options holds option values.
+type Options struct {
+	window time.Duration
+}
+
+// Option sets an option for the emitter.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithWindow sets how long repeats of an entry are suppressed. At the end of
+// each window a rollup entry is emitted for every entry that was repeated, and
+// the next occurrence of it is emitted again.
+//
+// If this option is not specified, or d is not positive, DefaultWindow is used.
+func WithWindow(d time.Duration) Option {
+	return func(o *Options) { o.window = d }
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"path"
@@ -21,35 +23,66 @@ var DefaultLogger = alog.New(alog.WithEmitter(Emitter()))
 
 type contextKey string
 
-var (
+// The keys are constants so that converting them to interface{} for
+// ctx.Value doesn't allocate.
+const (
 	severityKey    = contextKey("severity")
 	minSeverityKey = contextKey("minSeverity")
 	requestKey     = contextKey("request")
 	statusKey      = contextKey("status")
 	latencyKey     = contextKey("latency")
+	reqSizeKey     = contextKey("requestSize")
+	respSizeKey    = contextKey("responseSize")
+	connKey        = contextKey("connection")
 	traceKey       = contextKey("trace")
 	spanKey        = contextKey("span")
+	sampledKey     = contextKey("sampled")
+	traceStateKey  = contextKey("traceState")
 )
 
 // WithSeverity returns a copy of parent with the specified severity value.
+//
+// The level of the entry (see alog.WithLevel), if set, takes precedence over
+// the severity set here.
 func WithSeverity(parent context.Context, severity string) context.Context {
 	return context.WithValue(parent, severityKey, severity)
 }
 
 // WithMinSeverity sets the minimum severity to log.  Use one of the Severity*
 // constants.
+//
+// It's equivalent to alog.WithMinLevel(parent, LevelForSeverity(severity)), so
+// leveled.Filtered loggers honor it too.
 func WithMinSeverity(parent context.Context, severity string) context.Context {
+	if level := LevelForSeverity(severity); level != alog.LevelNone {
+		return alog.WithMinLevel(parent, level)
+	}
 	return context.WithValue(parent, minSeverityKey, severity)
 }
 
 // WithRequest returns a copy of parent with the specified http.Request value.
-// It also calls WithRequestTrace to add trace information to the context.
-func WithRequest(parent context.Context, req *http.Request) context.Context {
+// It also calls WithRequestTrace with headers to add trace information to the
+// context.
+func WithRequest(parent context.Context, req *http.Request, headers ...TraceHeader) context.Context {
 	ctx := context.WithValue(parent, requestKey, req)
-	ctx = WithRequestTrace(ctx, req)
+	ctx = WithRequestTrace(ctx, req, headers...)
 	return ctx
 }
 
+// ReplaceRequest returns a copy of parent with the specified http.Request value,
+// like WithRequest, but it leaves the trace information in parent as it is.
+// Emitters that wrap this one use it to pass on a modified copy of the request.
+func ReplaceRequest(parent context.Context, req *http.Request) context.Context {
+	return context.WithValue(parent, requestKey, req)
+}
+
+// RequestFromContext returns the http.Request set by WithRequest, or nil if
+// there isn't one.
+func RequestFromContext(ctx context.Context) *http.Request {
+	req, _ := ctx.Value(requestKey).(*http.Request)
+	return req
+}
+
 // WithTrace returns a copy of parent with the specified Trace ID value.
 func WithTrace(parent context.Context, trace string) context.Context {
 	return context.WithValue(parent, traceKey, trace)
@@ -74,37 +107,6 @@ func SpanDecimalToHex(spanID uint64) string {
 	return spanIDHex
 }
 
-// TraceFromRequest returns a trace and/or span from a http.Request.
-func TraceFromRequest(req *http.Request) (trace string, span string) {
-	traceHeader := req.Header.Get("X-Cloud-Trace-Context")
-	if traceHeader != "" {
-		traceSpan := strings.Split(traceHeader, "/")
-
-		trace = traceSpan[0]
-		if len(traceSpan) > 1 {
-			spanID, err := strconv.ParseUint(traceSpan[1], 10, 64)
-			if err == nil {
-				span = SpanDecimalToHex(spanID)
-			}
-		}
-	}
-	return
-}
-
-// WithRequestTrace returns a copy of parent with the trace information from
-// the specified http.Request.
-func WithRequestTrace(parent context.Context, req *http.Request) context.Context {
-	ctx := parent
-	trace, span := TraceFromRequest(req)
-	if trace != "" {
-		ctx = WithTrace(ctx, trace)
-	}
-	if span != "" {
-		ctx = WithSpan(ctx, span)
-	}
-	return ctx
-}
-
 // WithRequestStatus returns a copy of the parent with the specified HTTP return status code.
 func WithRequestStatus(parent context.Context, status int) context.Context {
 	return context.WithValue(parent, statusKey, status)
@@ -115,11 +117,28 @@ func WithRequestLatency(parent context.Context, latency time.Duration) context.C
 	return context.WithValue(parent, latencyKey, latency)
 }
 
+// WithRequestSize returns a copy of the parent with the specified size of the
+// HTTP request in bytes.
+func WithRequestSize(parent context.Context, size int64) context.Context {
+	return context.WithValue(parent, reqSizeKey, size)
+}
+
+// WithResponseSize returns a copy of the parent with the specified size of the
+// HTTP response in bytes.
+func WithResponseSize(parent context.Context, size int64) context.Context {
+	return context.WithValue(parent, respSizeKey, size)
+}
+
+// WithRequestConnection returns a copy of the parent that adds the remoteIp,
+// serverIp and protocol of the request set with WithRequest to its httpRequest
+// object. They identify the client, so they're meant for the one entry that
+// logs the request, not for every entry logged while handling it.
+func WithRequestConnection(parent context.Context) context.Context {
+	return context.WithValue(parent, connKey, true)
+}
+
 func jsonString(w *bytes.Buffer, s string) {
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
-	enc.Encode(s)
-	w.Truncate(w.Len() - 1)
+	internal.JSONString(w, s)
 }
 
 func jsonKey(w *bytes.Buffer, s string) {
@@ -136,6 +155,7 @@ var reservedKeys = map[string]bool{
 	"logging.googleapis.com/trace":          true,
 	"message":                               true,
 	"severity":                              true,
+	"stack_trace":                           true,
 	"time":                                  true,
 }
 
@@ -152,6 +172,9 @@ func defaultTraceExtractor(ctx context.Context) SpanContext {
 		sctx.SpanID = spanV.(string)
 		sctx.Sampled = true
 	}
+	if sampled, ok := ctx.Value(sampledKey).(bool); ok {
+		sctx.Sampled = sampled
+	}
 	return sctx
 }
 
@@ -187,18 +210,36 @@ func sortedMapKeys(m map[string][]string) []string {
 	return out
 }
 
+// skipHeaders are the request headers left out of httpHeaders, either because
+// they're written elsewhere or because they carry credentials.
 var skipHeaders = map[string]bool{
+	"Authorization":         true,
+	"Cookie":                true,
+	"Proxy-Authorization":   true,
 	"Referer":               true,
 	"Referrer":              true,
 	"User-Agent":            true,
 	"X-Cloud-Trace-Context": true,
+	"Traceparent":           true,
+	"Tracestate":            true,
+}
+
+// addrHost returns the host part of addr, an address in host:port form, or addr
+// if it isn't in that form.
+func addrHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
 }
 
 func jsonHTTPRequest(ctx context.Context, w *bytes.Buffer) {
 	var (
-		request *http.Request
-		status  int
-		latency time.Duration
+		request  *http.Request
+		status   int
+		latency  time.Duration
+		reqSize  int64 = -1
+		respSize int64 = -1
 	)
 
 	reqV := ctx.Value(requestKey)
@@ -213,32 +254,51 @@ func jsonHTTPRequest(ctx context.Context, w *bytes.Buffer) {
 	if latencyV != nil {
 		latency = latencyV.(time.Duration)
 	}
+	if v, ok := ctx.Value(reqSizeKey).(int64); ok {
+		reqSize = v
+	}
+	if v, ok := ctx.Value(respSizeKey).(int64); ok {
+		respSize = v
+	}
 
-	if request == nil && status <= 0 && latency == 0 {
+	if request == nil && status <= 0 && latency == 0 && reqSize < 0 && respSize < 0 {
 		return
 	}
 
 	jsonKey(w, "httpRequest")
 	w.WriteByte('{')
 
+	// sep writes the separator ahead of every field but the first.
+	first := true
+	sep := func() {
+		if !first {
+			w.WriteString(", ")
+		}
+		first = false
+	}
+
 	if status > 0 {
+		sep()
 		jsonKey(w, "status")
 		internal.Itoa(w, uint(status))
-		if latency > 0 || request != nil {
-			w.WriteString(", ")
-		}
+	}
+
+	if respSize >= 0 {
+		sep()
+		jsonKey(w, "responseSize")
+		internal.Itoa(w, uint(respSize))
 	}
 
 	if latency > 0 {
+		sep()
 		jsonKey(w, "latency")
-		latencyStr := strconv.FormatFloat(latency.Seconds(), 'f', -1, 64) + "s"
-		jsonString(w, latencyStr)
-		if request != nil {
-			w.WriteString(", ")
-		}
+		var buf [32]byte
+		b := strconv.AppendFloat(buf[:0], latency.Seconds(), 'f', -1, 64)
+		internal.JSONBytes(w, append(b, 's'))
 	}
 
 	if request != nil {
+		sep()
 		jsonKey(w, "requestMethod")
 		jsonString(w, request.Method)
 		w.WriteString(", ")
@@ -247,7 +307,15 @@ func jsonHTTPRequest(ctx context.Context, w *bytes.Buffer) {
 		u.Fragment = ""
 		jsonKey(w, "requestUrl")
 		jsonString(w, u.String())
+	}
+
+	if reqSize >= 0 {
+		sep()
+		jsonKey(w, "requestSize")
+		internal.Itoa(w, uint(reqSize))
+	}
 
+	if request != nil {
 		if request.UserAgent() != "" {
 			w.WriteString(", ")
 			jsonKey(w, "userAgent")
@@ -261,6 +329,26 @@ func jsonHTTPRequest(ctx context.Context, w *bytes.Buffer) {
 		}
 	}
 
+	if request != nil && ctx.Value(connKey) != nil {
+		if request.RemoteAddr != "" {
+			w.WriteString(", ")
+			jsonKey(w, "remoteIp")
+			jsonString(w, addrHost(request.RemoteAddr))
+		}
+
+		if addr, ok := request.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+			w.WriteString(", ")
+			jsonKey(w, "serverIp")
+			jsonString(w, addrHost(addr.String()))
+		}
+
+		if request.Proto != "" {
+			w.WriteString(", ")
+			jsonKey(w, "protocol")
+			jsonString(w, request.Proto)
+		}
+	}
+
 	w.WriteByte('}')
 	w.WriteString(", ")
 
@@ -322,6 +410,35 @@ func jsonHTTPRequest(ctx context.Context, w *bytes.Buffer) {
 
 }
 
+// jsonStackTrace writes the stack of e as a string in the format of a Go panic,
+// which is what Cloud Error Reporting expects: a "panic:" line with the
+// message, then the stack of the goroutine as runtime/debug.Stack writes it.
+// Function arguments aren't captured, so they are elided.
+func jsonStackTrace(w *bytes.Buffer, e *alog.Entry) {
+	st := internal.GetBuffer()
+	defer internal.PutBuffer(st)
+
+	st.WriteString("panic: ")
+	st.WriteString(e.Msg)
+	st.WriteString("\n\ngoroutine ")
+	internal.Itoa(st, uint(e.Goroutine))
+	st.WriteString(" [running]:\n")
+	for _, f := range e.Stack {
+		st.WriteString(f.Function)
+		st.WriteString("(...)\n\t")
+		st.WriteString(f.File)
+		st.WriteByte(':')
+		internal.Itoa(st, uint(f.Line))
+		if f.Offset != 0 {
+			var hex [16]byte
+			st.WriteString(" +0x")
+			st.Write(strconv.AppendUint(hex[:0], uint64(f.Offset), 16))
+		}
+		st.WriteByte('\n')
+	}
+	internal.JSONBytes(w, st.Bytes())
+}
+
 // Emitter emits log messages as single lines of JSON.
 //
 // Logs are output to w. Every entry generates a single Write call to w, and
@@ -350,11 +467,15 @@ func Emitter(opt ...Option) alog.Emitter {
 		b.WriteByte('{')
 
 		jsonKey(b, "time")
-		jsonString(b, e.Time.UTC().Format(time.RFC3339Nano))
+		var tb [64]byte
+		internal.JSONBytes(b, e.Time.UTC().AppendFormat(tb[:0], time.RFC3339Nano))
 		b.WriteString(", ")
 
-		severity := ctx.Value(severityKey)
-		if severity != nil {
+		if e.Level != alog.LevelNone {
+			jsonKey(b, "severity")
+			jsonString(b, SeverityForLevel(e.Level))
+			b.WriteString(", ")
+		} else if severity := ctx.Value(severityKey); severity != nil {
 			jsonKey(b, "severity")
 			jsonString(b, severity.(string))
 			b.WriteString(", ")
@@ -364,15 +485,8 @@ func Emitter(opt ...Option) alog.Emitter {
 
 		jsonTrace(ctx, o, b)
 
-		// Get all the tags and take their positions in the list.  If there are
-		// tags with the same key (first element of the string tuple) the latest
-		// tag will take precedence.
-		tagPositions := make(map[string]int, len(e.Tags))
-		for i, t := range e.Tags {
-			tagPositions[t[0]] = i
-		}
-		for i, tag := range e.Tags {
-			if tagPositions[tag[0]] != i || reservedKeys[tag[0]] {
+		for _, tag := range e.Tags {
+			if reservedKeys[tag[0]] {
 				continue
 			}
 			jsonKey(b, tag[0])
@@ -380,13 +494,9 @@ func Emitter(opt ...Option) alog.Emitter {
 			b.WriteString(", ")
 		}
 
-		sTagPositions := make(map[string]int, len(e.STags))
-		for i, t := range e.STags {
-			sTagPositions[t.Key] = i
-		}
-		for i, sTag := range e.STags {
-			_, asStringTag := tagPositions[sTag.Key] // has this already been used as a string tag?
-			if sTagPositions[sTag.Key] != i || asStringTag || reservedKeys[sTag.Key] {
+		for _, sTag := range e.STags {
+			// A string tag with the same key takes precedence.
+			if internal.HasTag(e.Tags, sTag.Key) || reservedKeys[sTag.Key] {
 				continue
 			}
 
@@ -397,6 +507,7 @@ func Emitter(opt ...Option) alog.Emitter {
 				b.Write(marshalled)
 			} else {
 				jsonString(b, "json marshal err: "+marshalErr.Error())
+				alog.ReportError(ctx, o.errorHandler, e, fmt.Errorf("marshalling sTag %q: %w", sTag.Key, marshalErr))
 			}
 
 			b.WriteString(", ")
@@ -420,15 +531,23 @@ func Emitter(opt ...Option) alog.Emitter {
 			b.WriteString(", ")
 		}
 
+		if len(e.Stack) > 0 {
+			jsonKey(b, "stack_trace")
+			jsonStackTrace(b, e)
+			b.WriteString(", ")
+		}
+
 		jsonKey(b, "message")
 		jsonString(b, e.Msg)
 
 		b.WriteString("}\n")
 
-		if ctx.Value(requestKey) == nil {
-			wApp.Write(b.Bytes())
-		} else {
-			wReq.Write(b.Bytes())
+		w := wApp
+		if ctx.Value(requestKey) != nil {
+			w = wReq
+		}
+		if _, err := w.Write(b.Bytes()); err != nil {
+			alog.ReportError(ctx, o.errorHandler, e, err)
 		}
 	})
 }
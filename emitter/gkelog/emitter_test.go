@@ -3,14 +3,20 @@ package gkelog
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/leveled"
 )
 
 var zeroTimeOpt = alog.OverrideTimestamp(func() time.Time { return time.Time{} })
@@ -23,7 +29,7 @@ func TestCaller(t *testing.T) {
 
 	l.Print(ctx, "test")
 
-	want := `{"time":"0001-01-01T00:00:00Z", "logging.googleapis.com/sourceLocation":{"file":"emitter_test.go", "line":"24"}, "message":"test"}` + "\n"
+	want := `{"time":"0001-01-01T00:00:00Z", "logging.googleapis.com/sourceLocation":{"file":"emitter_test.go", "line":"30"}, "message":"test"}` + "\n"
 	got := b.String()
 	if got != want {
 		t.Errorf("got:\n%s\nwant:\n%s", got, want)
@@ -251,6 +257,44 @@ func TestLatency(t *testing.T) {
 	}
 }
 
+func TestSizes(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := context.Background()
+	l := alog.New(alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt)
+
+	ctx = WithResponseSize(WithRequestSize(ctx, 0), 1024)
+
+	l.Print(ctx, "test")
+
+	want := `{"time":"0001-01-01T00:00:00Z", "httpRequest":{"responseSize":1024, "requestSize":0}, "message":"test"}` + "\n"
+	got := b.String()
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRequestConnection(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("Cookie", "session=abc")
+	req.Header.Set("Proxy-Authorization", "Basic abc")
+	req.Header.Set("Dnt", "1")
+	ctx := WithRequest(context.Background(), req)
+
+	l.Print(ctx, "test")
+	l.Print(WithRequestConnection(ctx), "test")
+
+	want := `{"time":"0001-01-01T00:00:00Z", "httpRequest":{"requestMethod":"GET", "requestUrl":"/test"}, "httpHeaders":{"Dnt":["1"]}, "message":"test"}` + "\n" +
+		`{"time":"0001-01-01T00:00:00Z", "httpRequest":{"requestMethod":"GET", "requestUrl":"/test", "remoteIp":"192.0.2.1", "protocol":"HTTP/1.1"}, "httpHeaders":{"Dnt":["1"]}, "message":"test"}` + "\n"
+	got := b.String()
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
 func TestWriters(t *testing.T) {
 	b0 := &bytes.Buffer{}
 	b1 := &bytes.Buffer{}
@@ -301,3 +345,206 @@ func TestReservedKeys(t *testing.T) {
 		t.Errorf("got:\n%s\nwant:\n%s", got, want)
 	}
 }
+
+func TestLeveled(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := context.Background()
+	l := leveled.Default(alog.New(alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt))
+
+	l.Warning(WithSeverity(ctx, SeverityError), "test")
+
+	want := `{"time":"0001-01-01T00:00:00Z", "severity":"WARNING", "message":"test"}` + "\n"
+	got := b.String()
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSeverityForLevel(t *testing.T) {
+	for s := range severityPriority {
+		if got := SeverityForLevel(LevelForSeverity(s)); got != s {
+			t.Errorf("SeverityForLevel(LevelForSeverity(%q)) = %q", s, got)
+		}
+	}
+	for l := leveled.Debug; l <= leveled.Critical; l++ {
+		if got, want := SeverityForLevel(l.AlogLevel()), strings.ToUpper(l.String()); got != want {
+			t.Errorf("SeverityForLevel(%v) = %q, want %q", l, got, want)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestErrorHandler(t *testing.T) {
+	var errs []error
+	h := func(ctx context.Context, e *alog.Entry, err error) {
+		errs = append(errs, err)
+	}
+	l := alog.New(alog.WithEmitter(Emitter(WithWriter(failingWriter{}))), alog.WithErrorHandler(h))
+
+	l.Print(alog.AddStructuredTags(context.Background(), alog.STag{Key: "f", Val: func() {}}), "test")
+
+	if len(errs) != 2 || !strings.HasPrefix(errs[0].Error(), `marshalling sTag "f"`) || errs[1] != io.ErrClosedPipe {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := &alog.Entry{
+		Level:     alog.LevelError,
+		Msg:       "index out of range",
+		Goroutine: 18,
+		Stack: []alog.Frame{
+			{Function: "main.(*server).handle", File: "/src/server.go", Line: 42, Offset: 0x1f},
+			{Function: "main.lookup", File: "/src/server.go", Line: 12},
+			{Function: "net/http.HandlerFunc.ServeHTTP", File: "/usr/local/go/src/net/http/server.go", Line: 2166, Offset: 0x29},
+		},
+	}
+	Emitter(WithWriter(b)).Emit(context.Background(), e)
+
+	// The layout of a Go panic, which Error Reporting parses. Arguments
+	// aren't captured, and inlined calls have no offset, as in tracebacks.
+	const sample = `panic: index out of range
+
+goroutine 18 [running]:
+main.(*server).handle(...)
+	/src/server.go:42 +0x1f
+main.lookup(...)
+	/src/server.go:12
+net/http.HandlerFunc.ServeHTTP(...)
+	/usr/local/go/src/net/http/server.go:2166 +0x29
+`
+	var got struct {
+		StackTrace string `json:"stack_trace"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.StackTrace != sample {
+		t.Errorf("got:\n%s\nwant:\n%s", got.StackTrace, sample)
+	}
+}
+
+func TestCapturedStackTrace(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(WithWriter(b))), alog.WithStack(alog.LevelError))
+	l.Print(alog.WithLevel(context.Background(), alog.LevelError), "test")
+	stack := string(debug.Stack())
+
+	var got struct {
+		StackTrace string `json:"stack_trace"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(got.StackTrace, "\n")
+	// debug.Stack starts with the same goroutine, and the frames of this
+	// test and its callers.
+	if header := strings.SplitN(stack, "\n", 2)[0]; lines[0] != "panic: test" || lines[2] != header {
+		t.Errorf("got:\n%s\nwant header %q", got.StackTrace, header)
+	}
+	if !strings.HasPrefix(lines[3], "github.com/vimeo/alog/v3/emitter/gkelog.TestCapturedStackTrace(") ||
+		!regexp.MustCompile(`^\t\S+/emitter_test\.go:\d+ \+0x[0-9a-f]+$`).MatchString(lines[4]) {
+		t.Errorf("got:\n%s", got.StackTrace)
+	}
+}
+
+func TestErrorTag(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt)
+
+	err := fmt.Errorf("saving: %w", io.ErrShortWrite)
+	l.Print(alog.AddStructuredTags(context.Background(), alog.ErrorTag("error", err)), "test")
+
+	want := `{"time":"0001-01-01T00:00:00Z", "error":{"message":"saving: short write","type":"*fmt.wrapError","wrapped":[{"message":"short write","type":"*errors.errorString"}]}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLazyTags(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := WithMinSeverity(context.Background(), SeverityWarning)
+	l := alog.New(alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt)
+
+	computed := 0
+	ctx = alog.AddLazyTags(ctx, alog.LazyTag{Key: "lazy", Val: func() string {
+		computed++
+		return "value"
+	}})
+	ctx = alog.AddStructuredTags(ctx, alog.STag{Key: "slazy", Val: alog.ValuerFunc(func() interface{} {
+		computed++
+		return []int{1}
+	})})
+	LogInfo(ctx, l, "NOT LOGGED")
+	LogError(ctx, l, "LOGGED")
+
+	want := `{"time":"0001-01-01T00:00:00Z", "severity":"ERROR", "lazy":"value", "slazy":[1], "message":"LOGGED"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if computed != 2 {
+		t.Errorf("lazy values computed %d times, want 2", computed)
+	}
+}
+
+func BenchmarkEmitter(b *testing.B) {
+	l := alog.New(alog.WithEmitter(Emitter(WithWriter(io.Discard))))
+	ctx := alog.AddTags(context.Background(), "component", "billing", "request", "1234", "user", "5678")
+	ctx = alog.WithLevel(ctx, alog.LevelInfo)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Output(ctx, 1, "a plain message")
+	}
+}
+
+func TestVModule(t *testing.T) {
+	b := &bytes.Buffer{}
+	ctx := context.Background()
+	m, err := alog.ParseVModule("other=debug,gkelog=warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := alog.New(alog.WithCaller(), alog.WithFilter(VModuleFilter(m)), alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt)
+	// Without the caller, the overrides can't apply.
+	noCaller := alog.New(alog.WithFilter(VModuleFilter(m)), alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt)
+
+	LogInfo(ctx, l, "NOT LOGGED")
+	NewSeverityLogger(l).Warning(ctx, "logged by override")
+	LogInfo(WithMinSeverity(ctx, SeverityDebug), l, "logged by context")
+	l.Print(alog.WithLevel(ctx, alog.LevelDebug), "NOT LOGGED")
+	m.Set("emitter_test=critical")
+	LogError(ctx, l, "NOT LOGGED")
+	LogError(ctx, noCaller, "logged without caller")
+
+	// Dropped entries are never completed.
+	resolved := 0
+	lazy := alog.AddLazyTags(alog.CaptureStack(ctx), alog.LazyTag{Key: "lazy", Val: func() string {
+		resolved++
+		return "x"
+	}})
+	LogError(lazy, l, "NOT LOGGED")
+	if resolved != 0 {
+		t.Errorf("lazy tag of a dropped entry resolved %d times", resolved)
+	}
+
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var e struct{ Severity, Message string }
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e.Severity+" "+e.Message)
+	}
+	want := []string{"WARNING logged by override", "INFO logged by context", "ERROR logged without caller"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
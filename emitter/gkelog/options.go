@@ -3,6 +3,8 @@ package gkelog
 import (
 	"context"
 	"io"
+
+	"github.com/vimeo/alog/v3"
 )
 
 // Options holds option values.
@@ -11,6 +13,7 @@ type Options struct {
 	appWriter     io.Writer
 	spanExtractor TraceSpanExtractor
 	shortfile     bool
+	errorHandler  alog.ErrorHandler
 }
 
 // Option sets an option for the emitter.
@@ -66,3 +69,12 @@ type TraceSpanExtractor func(context.Context) SpanContext
 func WithTraceSpanExtractor(extractor TraceSpanExtractor) Option {
 	return func(o *Options) { o.spanExtractor = extractor }
 }
+
+// WithErrorHandler sets the handler for errors writing entries or marshalling
+// structured tags.
+//
+// If this option is not specified, the handler set on the Logger with
+// alog.WithErrorHandler is used, if any.
+func WithErrorHandler(h alog.ErrorHandler) Option {
+	return func(o *Options) { o.errorHandler = h }
+}
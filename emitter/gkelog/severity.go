@@ -34,17 +34,100 @@ var severityPriority map[string]uint8 = map[string]uint8{
 	SeverityDefault:   8, // default will almost always log and should probably not be used
 }
 
+// severityLevels maps each severity to its alog.Level.
+var severityLevels = map[string]alog.Level{
+	SeverityDefault:   alog.LevelNone,
+	SeverityDebug:     alog.LevelDebug,
+	SeverityInfo:      alog.LevelInfo,
+	SeverityNotice:    alog.LevelNotice,
+	SeverityWarning:   alog.LevelWarning,
+	SeverityError:     alog.LevelError,
+	SeverityCritical:  alog.LevelCritical,
+	SeverityAlert:     alog.LevelAlert,
+	SeverityEmergency: alog.LevelEmergency,
+}
+
+// levelPriority returns the priority of the severity for l. LevelNone has the
+// lowest priority, so that every entry is logged.
+func levelPriority(l alog.Level) uint8 {
+	if l == alog.LevelNone {
+		return 0
+	}
+	return severityPriority[SeverityForLevel(l)]
+}
+
+// SeverityForLevel returns the Severity* constant for an alog.Level. Use
+// SeverityForLevel(l.AlogLevel()) for a leveled.Level.
+func SeverityForLevel(l alog.Level) string {
+	switch l {
+	case alog.LevelDebug:
+		return SeverityDebug
+	case alog.LevelInfo:
+		return SeverityInfo
+	case alog.LevelNotice:
+		return SeverityNotice
+	case alog.LevelWarning:
+		return SeverityWarning
+	case alog.LevelError:
+		return SeverityError
+	case alog.LevelCritical:
+		return SeverityCritical
+	case alog.LevelAlert:
+		return SeverityAlert
+	case alog.LevelEmergency:
+		return SeverityEmergency
+	}
+	return SeverityDefault
+}
+
+// LevelForSeverity returns the alog.Level for one of the Severity* constants.
+// SeverityDefault and unknown severities map to alog.LevelNone. Use
+// leveled.FromAlogLevel(LevelForSeverity(s)) for a leveled.Level.
+func LevelForSeverity(severity string) alog.Level {
+	return severityLevels[severity]
+}
+
+// VModuleFilter returns an alog.Filter that applies overrides of the minimum
+// severity of the entries logged from particular files or packages, like
+// leveled.WithVModule. Pass it to alog.WithFilter, so that the entries it
+// rejects are dropped before their lazy tags are resolved or their stack is
+// captured. Entries are matched by their caller, so the Logger must also be
+// created with alog.WithCaller for the overrides to apply. A minimum severity
+// set on the context with WithMinSeverity or alog.WithMinLevel takes
+// precedence over the overrides, and entries without a level are always
+// accepted.
+//
+// The overrides can be changed with m.Set while the filter is in use.
+func VModuleFilter(m *alog.VModule) alog.Filter {
+	return func(ctx context.Context, e *alog.Entry) bool {
+		if e.Level == alog.LevelNone {
+			return true
+		}
+		if _, ok := alog.MinLevelFromContext(ctx); ok || ctx.Value(minSeverityKey) != nil {
+			return true
+		}
+		min, ok := m.MinLevelForFile(e.File)
+		return !ok || levelPriority(e.Level) >= levelPriority(min)
+	}
+}
+
 // Separate private function so that LogSeverity and the other logs functions
 // will have the same stack frame depth and thus use the same calldepth value.
 // See https://golang.org/pkg/runtime/#Caller and
 // https://godoc.org/github.com/vimeo/alog#Logger.Output
 func logSeverity(ctx context.Context, logger *alog.Logger, s string, f string, v ...interface{}) {
 	minSeverity := uint8(0)
-	if minSeverityVal := ctx.Value(minSeverityKey); minSeverityVal != nil {
+	if l, ok := alog.MinLevelFromContext(ctx); ok {
+		minSeverity = levelPriority(l)
+	} else if minSeverityVal := ctx.Value(minSeverityKey); minSeverityVal != nil {
 		minSeverity = severityPriority[minSeverityVal.(string)]
 	}
 	if severityPriority[s] >= minSeverity {
-		ctx = WithSeverity(ctx, s)
+		if level := LevelForSeverity(s); level != alog.LevelNone {
+			ctx = alog.WithLevel(ctx, level)
+		} else {
+			ctx = WithSeverity(ctx, s)
+		}
 		logger.Output(ctx, 3, fmt.Sprintf(f, v...))
 	}
 }
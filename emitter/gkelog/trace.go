@@ -0,0 +1,198 @@
+package gkelog
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TraceHeader is a format of the headers that carry the trace context of an
+// HTTP request.
+type TraceHeader uint8
+
+const (
+	// CloudTraceContext is Google Cloud's X-Cloud-Trace-Context header, in
+	// the form TRACE_ID/SPAN_ID;o=OPTIONS, where SPAN_ID is decimal and the
+	// low bit of OPTIONS is set for sampled traces.
+	CloudTraceContext TraceHeader = iota + 1
+
+	// TraceParent is the W3C Trace Context traceparent header, in the form
+	// VERSION-TRACE_ID-PARENT_ID-FLAGS, along with its tracestate header.
+	// See https://www.w3.org/TR/trace-context/.
+	TraceParent
+)
+
+// defaultTraceHeaders are the trace headers read when none are given.
+// X-Cloud-Trace-Context comes first, so requests that have both headers keep
+// the trace they had before traceparent was read.
+var defaultTraceHeaders = []TraceHeader{CloudTraceContext, TraceParent}
+
+// SpanContextFromRequest returns the trace context of req and whether there was
+// a valid one. It's read from the given headers, in order of precedence: the
+// trace context is taken from the first of them that the request has in a valid
+// form, and formats left out aren't read. If no headers are given, it reads
+// CloudTraceContext, then TraceParent.
+//
+// For traceparent headers, the trace is sampled if the sampled flag is set.
+// X-Cloud-Trace-Context headers without options are reported as sampled, as
+// traces have always been.
+func SpanContextFromRequest(req *http.Request, headers ...TraceHeader) (SpanContext, bool) {
+	sc, _, ok := spanContextFromRequest(req, headers)
+	return sc, ok
+}
+
+// spanContextFromRequest is SpanContextFromRequest, also returning the
+// tracestate that goes with a traceparent header.
+func spanContextFromRequest(req *http.Request, headers []TraceHeader) (sc SpanContext, state string, ok bool) {
+	if len(headers) == 0 {
+		headers = defaultTraceHeaders
+	}
+	for _, h := range headers {
+		switch h {
+		case CloudTraceContext:
+			if sc, ok := parseCloudTraceContext(req.Header.Get("X-Cloud-Trace-Context")); ok {
+				return sc, "", true
+			}
+		case TraceParent:
+			// The header must not be repeated.
+			if v := req.Header.Values("Traceparent"); len(v) == 1 {
+				if sc, ok := parseTraceParent(v[0]); ok {
+					return sc, parseTraceState(req.Header.Values("Tracestate")), true
+				}
+			}
+		}
+	}
+	return SpanContext{}, "", false
+}
+
+// TraceFromRequest returns a trace and/or span from a http.Request, read from
+// the given headers. See SpanContextFromRequest.
+func TraceFromRequest(req *http.Request, headers ...TraceHeader) (trace string, span string) {
+	sc, _ := SpanContextFromRequest(req, headers...)
+	return sc.TraceID, sc.SpanID
+}
+
+// WithRequestTrace returns a copy of parent with the trace information from
+// the specified http.Request: the trace, the span, whether the trace is
+// sampled, and the tracestate of a traceparent header. It's read from the given
+// headers; see SpanContextFromRequest.
+func WithRequestTrace(parent context.Context, req *http.Request, headers ...TraceHeader) context.Context {
+	sc, state, ok := spanContextFromRequest(req, headers)
+	if !ok {
+		return parent
+	}
+	ctx := parent
+	if sc.TraceID != "" {
+		ctx = WithTrace(ctx, sc.TraceID)
+	}
+	if sc.SpanID != "" {
+		ctx = WithSpan(ctx, sc.SpanID)
+	}
+	ctx = WithTraceSampled(ctx, sc.Sampled)
+	if state != "" {
+		ctx = context.WithValue(ctx, traceStateKey, state)
+	}
+	return ctx
+}
+
+// WithTraceSampled returns a copy of parent recording whether the trace set
+// with WithTrace is sampled. Without it, traces are reported as sampled.
+func WithTraceSampled(parent context.Context, sampled bool) context.Context {
+	return context.WithValue(parent, sampledKey, sampled)
+}
+
+// TraceStateFromContext returns the W3C tracestate set by WithRequestTrace, or
+// "" if there isn't one, so it can be passed on with the trace.
+func TraceStateFromContext(ctx context.Context) string {
+	state, _ := ctx.Value(traceStateKey).(string)
+	return state
+}
+
+// parseCloudTraceContext parses an X-Cloud-Trace-Context header. A span that
+// isn't a decimal number is left out, as is the sampling decision if the
+// options aren't valid.
+func parseCloudTraceContext(h string) (sc SpanContext, ok bool) {
+	h, options, hasOptions := strings.Cut(h, ";")
+	trace, span, _ := strings.Cut(h, "/")
+	if trace == "" {
+		return SpanContext{}, false
+	}
+	sc.TraceID = trace
+	if spanID, err := strconv.ParseUint(span, 10, 64); err == nil {
+		sc.SpanID = SpanDecimalToHex(spanID)
+	}
+	sc.Sampled = true
+	if o, ok := strings.CutPrefix(options, "o="); hasOptions && ok {
+		if flags, err := strconv.ParseUint(o, 10, 8); err == nil {
+			sc.Sampled = flags&1 != 0
+		}
+	}
+	return sc, true
+}
+
+// parseTraceParent parses a traceparent header as the W3C spec says to: a
+// version it doesn't know is parsed as version 00, as long as it only adds
+// fields after the ones it knows.
+func parseTraceParent(h string) (sc SpanContext, ok bool) {
+	// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	const size = 2 + 1 + 32 + 1 + 16 + 1 + 2
+	if len(h) < size || h[2] != '-' || h[35] != '-' || h[52] != '-' {
+		return SpanContext{}, false
+	}
+	version, trace, span, flags := h[:2], h[3:35], h[36:52], h[53:55]
+	if !isLowerHex(version) || version == "ff" {
+		return SpanContext{}, false
+	}
+	if version == "00" && len(h) != size || len(h) > size && h[size] != '-' {
+		return SpanContext{}, false
+	}
+	if !isLowerHex(trace) || !isLowerHex(span) || !isLowerHex(flags) ||
+		isZeros(trace) || isZeros(span) {
+		return SpanContext{}, false
+	}
+	f, _ := strconv.ParseUint(flags, 16, 8)
+	return SpanContext{TraceID: trace, SpanID: span, Sampled: f&1 != 0}, true
+}
+
+// maxTraceStateMembers is the largest number of list members a tracestate may
+// have.
+const maxTraceStateMembers = 32
+
+// parseTraceState combines the tracestate headers into one list, dropping empty
+// members. It returns "" if a member isn't of the form key=value, or there are
+// too many of them, since the spec says to discard the whole list then.
+func parseTraceState(headers []string) string {
+	var members []string
+	for _, h := range headers {
+		for _, m := range strings.Split(h, ",") {
+			m = strings.TrimSpace(m)
+			if m == "" {
+				continue
+			}
+			key, _, ok := strings.Cut(m, "=")
+			if !ok || key == "" || strings.ContainsAny(key, " \t") {
+				return ""
+			}
+			members = append(members, m)
+		}
+	}
+	if len(members) > maxTraceStateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
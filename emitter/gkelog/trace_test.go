@@ -0,0 +1,147 @@
+package gkelog
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vimeo/alog/v3"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	for _, tt := range []struct {
+		h  string
+		sc SpanContext
+		ok bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-02", SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}, true},
+		// Later versions may add fields.
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03-extra", SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}, true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}, true},
+
+		{"", SpanContext{}, false},
+		{"garbage", SpanContext{}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", SpanContext{}, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01extra", SpanContext{}, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", SpanContext{}, false},
+		{"0g-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", SpanContext{}, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", SpanContext{}, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", SpanContext{}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", SpanContext{}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x", SpanContext{}, false},
+		{"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01", SpanContext{}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-600f067aa0ba902b7-01", SpanContext{}, false},
+	} {
+		sc, ok := parseTraceParent(tt.h)
+		if sc != tt.sc || ok != tt.ok {
+			t.Errorf("parseTraceParent(%q) = %+v, %v, want %+v, %v", tt.h, sc, ok, tt.sc, tt.ok)
+		}
+	}
+}
+
+func TestParseCloudTraceContext(t *testing.T) {
+	for _, tt := range []struct {
+		h  string
+		sc SpanContext
+		ok bool
+	}{
+		{"a2fbf27a2ed90077e0d4af0e40a241f9/12690385211238481741", SpanContext{TraceID: "a2fbf27a2ed90077e0d4af0e40a241f9", SpanID: "b01d4e1cf2bd7f4d", Sampled: true}, true},
+		{"a2fbf27a2ed90077e0d4af0e40a241f9/12690385211238481741;o=1", SpanContext{TraceID: "a2fbf27a2ed90077e0d4af0e40a241f9", SpanID: "b01d4e1cf2bd7f4d", Sampled: true}, true},
+		{"a2fbf27a2ed90077e0d4af0e40a241f9/12690385211238481741;o=0", SpanContext{TraceID: "a2fbf27a2ed90077e0d4af0e40a241f9", SpanID: "b01d4e1cf2bd7f4d"}, true},
+		{"a2fbf27a2ed90077e0d4af0e40a241f9;o=0", SpanContext{TraceID: "a2fbf27a2ed90077e0d4af0e40a241f9"}, true},
+		{"a2fbf27a2ed90077e0d4af0e40a241f9/notanumber;o=x", SpanContext{TraceID: "a2fbf27a2ed90077e0d4af0e40a241f9", Sampled: true}, true},
+		{"", SpanContext{}, false},
+		{"/123;o=1", SpanContext{}, false},
+	} {
+		sc, ok := parseCloudTraceContext(tt.h)
+		if sc != tt.sc || ok != tt.ok {
+			t.Errorf("parseCloudTraceContext(%q) = %+v, %v, want %+v, %v", tt.h, sc, ok, tt.sc, tt.ok)
+		}
+	}
+}
+
+func TestParseTraceState(t *testing.T) {
+	many := make([]string, maxTraceStateMembers+1)
+	for i := range many {
+		many[i] = "k=v"
+	}
+	for _, tt := range []struct {
+		headers []string
+		want    string
+	}{
+		{nil, ""},
+		{[]string{"congo=t61rcWkgMzE"}, "congo=t61rcWkgMzE"},
+		{[]string{"rojo=00f067aa0ba902b7, ,congo=t61rcWkgMzE", "vendor@tenant=x"}, "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE,vendor@tenant=x"},
+		{[]string{"rojo=1,nokey"}, ""},
+		{[]string{"=1"}, ""},
+		{many, ""},
+	} {
+		if got := parseTraceState(tt.headers); got != tt.want {
+			t.Errorf("parseTraceState(%q) = %q, want %q", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestTraceHeaders(t *testing.T) {
+	const (
+		parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+		cloud  = "a2fbf27a2ed90077e0d4af0e40a241f9/12690385211238481741;o=1"
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Traceparent", parent)
+	req.Header.Set("Tracestate", "congo=t61rcWkgMzE")
+	req.Header.Set("X-Cloud-Trace-Context", cloud)
+
+	log := func(headers ...TraceHeader) string {
+		b := &bytes.Buffer{}
+		l := alog.New(alog.WithEmitter(Emitter(WithWriter(b))), zeroTimeOpt)
+		l.Print(WithRequestTrace(context.Background(), req, headers...), "test")
+		return b.String()
+	}
+	w3c := `{"time":"0001-01-01T00:00:00Z", "logging.googleapis.com/trace":"4bf92f3577b34da6a3ce929d0e0e4736", "logging.googleapis.com/spanId":"00f067aa0ba902b7", "logging.googleapis.com/trace_sampled":false, "message":"test"}` + "\n"
+	google := `{"time":"0001-01-01T00:00:00Z", "logging.googleapis.com/trace":"a2fbf27a2ed90077e0d4af0e40a241f9", "logging.googleapis.com/spanId":"b01d4e1cf2bd7f4d", "logging.googleapis.com/trace_sampled":true, "message":"test"}` + "\n"
+
+	if got := log(); got != google {
+		t.Errorf("default:\ngot:\n%s\nwant:\n%s", got, google)
+	}
+	if got := TraceStateFromContext(WithRequestTrace(context.Background(), req)); got != "" {
+		t.Errorf("TraceStateFromContext with X-Cloud-Trace-Context = %q", got)
+	}
+
+	if got := log(TraceParent, CloudTraceContext); got != w3c {
+		t.Errorf("TraceParent first:\ngot:\n%s\nwant:\n%s", got, w3c)
+	}
+	if got := TraceStateFromContext(WithRequestTrace(context.Background(), req, TraceParent)); got != "congo=t61rcWkgMzE" {
+		t.Errorf("TraceStateFromContext = %q", got)
+	}
+
+	// Without an X-Cloud-Trace-Context header, the default reads traceparent.
+	req.Header.Del("X-Cloud-Trace-Context")
+	if got := log(); got != w3c {
+		t.Errorf("only traceparent:\ngot:\n%s\nwant:\n%s", got, w3c)
+	}
+	req.Header.Set("X-Cloud-Trace-Context", cloud)
+
+	// A malformed header falls through to the next format.
+	req.Header.Set("Traceparent", "00-bogus")
+	if got := log(TraceParent, CloudTraceContext); got != google {
+		t.Errorf("malformed traceparent:\ngot:\n%s\nwant:\n%s", got, google)
+	}
+	// So does a repeated one.
+	req.Header.Set("Traceparent", parent)
+	req.Header.Add("Traceparent", parent)
+	if got := log(TraceParent, CloudTraceContext); got != google {
+		t.Errorf("repeated traceparent:\ngot:\n%s\nwant:\n%s", got, google)
+	}
+
+	if got, want := log(TraceParent), `{"time":"0001-01-01T00:00:00Z", "message":"test"}`+"\n"; got != want {
+		t.Errorf("only TraceParent:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	if trace, span := TraceFromRequest(req, TraceParent); trace != "" || span != "" {
+		t.Errorf("TraceFromRequest = %q, %q", trace, span)
+	}
+}
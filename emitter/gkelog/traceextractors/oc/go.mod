@@ -1,11 +1,12 @@
 module github.com/vimeo/alog/emitter/gkelog/traceextractors/oc
 
-go 1.12
+go 1.21
 
 require (
-	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/vimeo/alog/v3 v3.5.0
 	go.opencensus.io v0.22.3
 )
 
+require github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
+
 replace github.com/vimeo/alog/v3 => ../../../../
@@ -1,11 +1,16 @@
 module github.com/vimeo/alog/emitter/gkelog/traceextractors/otel
 
-go 1.12
+go 1.21
 
 require (
 	github.com/vimeo/alog/v3 v3.5.0
 	go.opentelemetry.io/otel v0.2.2
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
 	google.golang.org/grpc v1.56.3 // indirect
+	google.golang.org/protobuf v1.30.0 // indirect
 )
 
 replace github.com/vimeo/alog/v3 => ../../../../
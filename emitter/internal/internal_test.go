@@ -2,9 +2,12 @@ package internal
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -92,3 +95,84 @@ func TestNonConcurrentWriter(t *testing.T) {
 		t.Errorf("nonConcurrentWriter did not detect concurrent write")
 	}
 }
+
+// encodeJSON is the reference for JSONString: encoding/json with HTML escaping
+// turned off, which is what the emitters used before.
+func encodeJSON(s string) string {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+var jsonSeeds = []string{
+	"",
+	"plain",
+	`"quoted"`,
+	"back\\slash",
+	"\b\f\n\r\t\x00\x1f\x7f",
+	"<html>&",
+	"h\u00e9llo, \u4e16\u754c \U0001f600",
+	"line\u2028para\u2029",
+	"\xff",
+	"a\xc3",
+	"\xed\xa0\x80",
+	"\xf4\x90\x80\x80",
+	"\ufffd",
+}
+
+func TestJSONString(t *testing.T) {
+	for _, s := range jsonSeeds {
+		b := &bytes.Buffer{}
+		JSONString(b, s)
+		want := encodeJSON(s)
+		if b.String() != want {
+			t.Errorf("JSONString(%q) = %s, want %s", s, b, want)
+		}
+		b.Reset()
+		JSONBytes(b, []byte(s))
+		if b.String() != want {
+			t.Errorf("JSONBytes(%q) = %s, want %s", s, b, want)
+		}
+	}
+}
+
+func FuzzJSONString(f *testing.F) {
+	for _, s := range jsonSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		b := &bytes.Buffer{}
+		JSONString(b, s)
+		if want := encodeJSON(s); b.String() != want {
+			t.Errorf("JSONString(%q) = %s, want %s", s, b, want)
+		}
+	})
+}
+
+func FuzzJSONBytes(f *testing.F) {
+	for _, s := range jsonSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, s []byte) {
+		b := &bytes.Buffer{}
+		JSONBytes(b, s)
+		if want := encodeJSON(string(s)); b.String() != want {
+			t.Errorf("JSONBytes(%q) = %s, want %s", s, b, want)
+		}
+	})
+}
+
+func BenchmarkJSONString(b *testing.B) {
+	for _, s := range []string{"a plain tag value", "a \"quoted\" value\nwith h\u00e9 and \xff"} {
+		b.Run(strconv.Quote(s), func(b *testing.B) {
+			buf := &bytes.Buffer{}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				JSONString(buf, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+const hex = "0123456789abcdef"
+
+// The output of encoding/json depends on the Go version, so these are taken
+// from it rather than hard coded.
+var (
+	// shortBF is whether encoding/json escapes '\b' and '\f' as \b and
+	// \f, which it does from Go 1.22, rather than as \u0008 and \u000c.
+	shortBF = func() bool {
+		b, _ := json.Marshal("\b")
+		return string(b) == `"\b"`
+	}()
+
+	// invalidUTF8 is what encoding/json writes in place of each byte of
+	// invalid UTF-8: the escape \ufffd, or the character itself in newer
+	// implementations.
+	invalidUTF8 = func() string {
+		b, _ := json.Marshal("\xff")
+		return string(b[1 : len(b)-1])
+	}()
+)
+
+// JSONString writes s to w as a JSON string, escaped the way encoding/json
+// does with HTML escaping turned off.
+func JSONString(w *bytes.Buffer, s string) {
+	w.Write(AppendJSON(w.AvailableBuffer(), s))
+}
+
+// JSONBytes is like JSONString, for a byte slice.
+func JSONBytes(w *bytes.Buffer, s []byte) {
+	w.Write(AppendJSON(w.AvailableBuffer(), s))
+}
+
+// AppendJSON appends s to dst as a quoted JSON string and returns the extended
+// buffer. The output is the same as encoding/json's with HTML escaping turned
+// off: quotes, backslashes and control characters are escaped, as are U+2028
+// and U+2029, and each byte of invalid UTF-8 is replaced with U+FFFD.
+func AppendJSON[S string | []byte](dst []byte, s S) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch {
+			case c == '"' || c == '\\':
+				dst = append(dst, '\\', c)
+			case c == '\n':
+				dst = append(dst, '\\', 'n')
+			case c == '\r':
+				dst = append(dst, '\\', 'r')
+			case c == '\t':
+				dst = append(dst, '\\', 't')
+			case c == '\b' && shortBF:
+				dst = append(dst, '\\', 'b')
+			case c == '\f' && shortBF:
+				dst = append(dst, '\\', 'f')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		// Converting at most utf8.UTFMax bytes doesn't allocate.
+		r, size := utf8.DecodeRuneInString(string(s[i:min(i+utf8.UTFMax, len(s))]))
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, invalidUTF8...)
+		case r == '\u2028' || r == '\u2029':
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,12 @@
+package internal
+
+// HasTag reports whether tags has a tag with key. Emitters use it to skip the
+// structured tags that share a key with a string tag.
+func HasTag(tags [][2]string, key string) bool {
+	for _, t := range tags {
+		if t[0] == key {
+			return true
+		}
+	}
+	return false
+}
@@ -28,7 +28,7 @@ func (o *SerializedWriter) Write(b []byte) (int, error) {
 
 // Itoa writes the string representation of an int to a Buffer.
 func Itoa(w *bytes.Buffer, i uint) {
-	buf := make([]byte, 20)
+	var buf [20]byte
 	p := len(buf) - 1
 	for i >= 10 {
 		q := i / 10
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 
@@ -15,10 +16,7 @@ import (
 var DefaultLogger = alog.New(alog.WithEmitter(Emitter(os.Stderr, WithShortFile(), WithUTC())))
 
 func jsonString(w *bytes.Buffer, s string) {
-	enc := json.NewEncoder(w)
-	enc.SetEscapeHTML(false)
-	enc.Encode(s)
-	w.Truncate(w.Len() - 1)
+	internal.JSONString(w, s)
 }
 
 // Emitter emits log messages as single lines of JSON.
@@ -51,6 +49,14 @@ func Emitter(w io.Writer, opt ...Option) alog.Emitter {
 	jsonString(b, timestampField)
 	timestampField = b.String()
 
+	levelField := o.levelField
+	if levelField == "" {
+		levelField = DefaultLevelField
+	}
+	b.Reset()
+	jsonString(b, levelField)
+	levelField = b.String()
+
 	callerField := o.callerField
 	if callerField == "" {
 		callerField = DefaultCallerField
@@ -81,7 +87,14 @@ func Emitter(w io.Writer, opt ...Option) alog.Emitter {
 			}
 			b.WriteString(timestampField)
 			b.WriteByte(':')
-			jsonString(b, e.Time.Format(timestampFormat))
+			var tb [64]byte
+			internal.JSONBytes(b, e.Time.AppendFormat(tb[:0], timestampFormat))
+			b.WriteString(", ")
+		}
+		if e.Level != alog.LevelNone {
+			b.WriteString(levelField)
+			b.WriteByte(':')
+			jsonString(b, e.Level.String())
 			b.WriteString(", ")
 		}
 		if o.flags&fileFlag != 0 && e.File != "" {
@@ -101,43 +114,35 @@ func Emitter(w io.Writer, opt ...Option) alog.Emitter {
 			fb.WriteString(file)
 			fb.WriteByte(':')
 			internal.Itoa(fb, line)
-			jsonString(b, fb.String())
+			internal.JSONBytes(b, fb.Bytes())
 			internal.PutBuffer(fb)
 			b.WriteString(", ")
 		}
 
-		tagPositions := make(map[string]int, len(e.Tags))
-		for i, tag := range e.Tags {
-			tagPositions[tag[0]] = i
-		}
-
 		if len(e.Tags) > 0 {
 			b.WriteString(`"tags":{`)
 			for i, tag := range e.Tags {
-				if tagPositions[tag[0]] != i {
-					continue
+				if i > 0 {
+					b.WriteString(", ")
 				}
 				jsonString(b, tag[0])
 				b.WriteByte(':')
 				jsonString(b, tag[1])
-				if i < len(e.Tags)-1 {
-					b.WriteString(", ")
-				}
 			}
 			b.WriteString("}, ")
 		}
 
 		if len(e.STags) > 0 {
-			sTagPositions := make(map[string]int, len(e.STags))
-			for i, tag := range e.STags {
-				sTagPositions[tag.Key] = i
-			}
 			b.WriteString(`"sTags":{`)
-			for i, tag := range e.STags {
-				_, asStringTag := tagPositions[tag.Key]
-				if sTagPositions[tag.Key] != i || asStringTag {
+			n := 0
+			for _, tag := range e.STags {
+				if internal.HasTag(e.Tags, tag.Key) {
 					continue
 				}
+				if n > 0 {
+					b.WriteString(", ")
+				}
+				n++
 
 				jsonString(b, tag.Key)
 				b.WriteByte(':')
@@ -147,12 +152,27 @@ func Emitter(w io.Writer, opt ...Option) alog.Emitter {
 					b.Write(marshalled)
 				} else {
 					jsonString(b, "json marshal err: "+marshalErr.Error())
+					alog.ReportError(ctx, o.errorHandler, e, fmt.Errorf("marshalling sTag %q: %w", tag.Key, marshalErr))
 				}
-				if i < len(e.Tags)-1 {
+			}
+			b.WriteString("}, ")
+		}
+
+		if len(e.Stack) > 0 {
+			b.WriteString(`"stack":[`)
+			for i, f := range e.Stack {
+				if i > 0 {
 					b.WriteString(", ")
 				}
+				b.WriteString(`{"function":`)
+				jsonString(b, f.Function)
+				b.WriteString(`, "file":`)
+				jsonString(b, f.File)
+				b.WriteString(`, "line":`)
+				internal.Itoa(b, uint(f.Line))
+				b.WriteByte('}')
 			}
-			b.WriteString("}, ")
+			b.WriteString("], ")
 		}
 
 		b.WriteString(messageField)
@@ -161,8 +181,8 @@ func Emitter(w io.Writer, opt ...Option) alog.Emitter {
 
 		b.WriteString("}\n")
 
-		// Writer error is swallowed, because checking errors on writing log
-		// lines is my personal conception of hell.
-		wOut.Write(b.Bytes())
+		if _, err := wOut.Write(b.Bytes()); err != nil {
+			alog.ReportError(ctx, o.errorHandler, e, err)
+		}
 	})
 }
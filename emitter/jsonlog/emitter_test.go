@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"testing"
 	"time"
 
@@ -29,7 +30,7 @@ func TestEmitter(t *testing.T) {
 	ctx = alog.AddStructuredTags(ctx, alog.STag{Key: "structured", Val: structuredVal}, alog.STag{Key: "other-struct", Val: structuredVal})
 	l.Print(ctx, "test")
 
-	want := `{"timestamp":"0001-01-01T00:00:00.000000000Z", "caller":"emitter_test.go:30", "tags":{"allthese":"tags", "andanother":"tag"}, "sTags":{"structured":{"x":1,"why":42}, "other-struct":{"x":1,"why":42}}, "message":"test"}` + "\n"
+	want := `{"timestamp":"0001-01-01T00:00:00.000000000Z", "caller":"emitter_test.go:31", "tags":{"allthese":"tags", "andanother":"tag"}, "sTags":{"structured":{"x":1,"why":42}, "other-struct":{"x":1,"why":42}}, "message":"test"}` + "\n"
 	got := b.String()
 	if got != want {
 		t.Errorf("got:\n%s\nwant:\n%s", got, want)
@@ -65,7 +66,7 @@ func TestCustomFieldNames(t *testing.T) {
 
 	l.Print(ctx, "test")
 
-	want := `{"ts":"0001-01-01T00:00:00.000000000Z", "called_at":"emitter_test.go:66", "msg":"test"}` + "\n"
+	want := `{"ts":"0001-01-01T00:00:00.000000000Z", "called_at":"emitter_test.go:67", "msg":"test"}` + "\n"
 	got := b.String()
 	if got != want {
 		t.Errorf("got:\n%s\nwant:\n%s", got, want)
@@ -140,3 +141,79 @@ func TestDuplicateTag(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestSTagSeparators(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(b, WithDateFormat(""))))
+
+	ctx := alog.AddTags(context.Background(), "a", "1", "b", "2", "a", "3")
+	ctx = alog.AddStructuredTags(ctx, alog.STag{Key: "n", Val: 1}, alog.STag{Key: "a", Val: 2})
+	l.Print(ctx, "")
+	const want = `{"tags":{"b":"2", "a":"3"}, "sTags":{"n":1}, "message":""}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got: %#q, want: %#q", got, want)
+	}
+	if !json.Valid(b.Bytes()) {
+		t.Errorf("invalid json: %s", b.String())
+	}
+}
+
+func TestLevel(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(b, WithDateFormat(""), WithLevelField("lvl"))))
+
+	l.Print(alog.WithLevel(context.Background(), alog.LevelWarning), "test")
+
+	want := `{"lvl":"warning", "message":"test"}` + "\n"
+	got := b.String()
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	b := &bytes.Buffer{}
+	var errs []error
+	l := alog.New(alog.WithEmitter(Emitter(b, WithDateFormat(""), WithErrorHandler(func(ctx context.Context, e *alog.Entry, err error) {
+		errs = append(errs, err)
+	}))))
+
+	l.Print(alog.AddStructuredTags(context.Background(), alog.STag{Key: "ch", Val: make(chan int)}), "test")
+
+	want := `{"sTags":{"ch":"json marshal err: json: unsupported type: chan int"}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if len(errs) != 1 || errs[0].Error() != `marshalling sTag "ch": json: unsupported type: chan int` {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestStack(t *testing.T) {
+	b := &bytes.Buffer{}
+	e := &alog.Entry{
+		Msg: "test",
+		Stack: []alog.Frame{
+			{Function: "main.f", File: "/src/main.go", Line: 12},
+			{Function: "main.main", File: "/src/main.go", Line: 5},
+		},
+	}
+	Emitter(b, WithDateFormat("")).Emit(context.Background(), e)
+
+	want := `{"stack":[{"function":"main.f", "file":"/src/main.go", "line":12}, {"function":"main.main", "file":"/src/main.go", "line":5}], "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func BenchmarkEmitter(b *testing.B) {
+	l := alog.New(alog.WithEmitter(Emitter(io.Discard)))
+	ctx := alog.AddTags(context.Background(), "component", "billing", "request", "1234", "user", "5678")
+	ctx = alog.WithLevel(ctx, alog.LevelInfo)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Output(ctx, 1, "a plain message")
+	}
+}
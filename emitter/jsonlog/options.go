@@ -2,6 +2,8 @@ package jsonlog
 
 import (
 	"io"
+
+	"github.com/vimeo/alog/v3"
 )
 
 const (
@@ -21,6 +23,10 @@ const (
 	// value. It is used if WithTimestampField is not specified.
 	DefaultTimestampField = "timestamp"
 
+	// DefaultLevelField is the default field name used for the level of
+	// the entry. It is used if WithLevelField is not specified.
+	DefaultLevelField = "level"
+
 	// DefaultCallerField is the default field name used for the caller
 	// information. It is used if WithCallerField is not specified.
 	DefaultCallerField = "caller"
@@ -33,11 +39,13 @@ const (
 // Options holds option values.
 type Options struct {
 	timestampField string
+	levelField     string
 	callerField    string
 	messageField   string
 	datefmt        string
 	flags          uint
 	writer         io.Writer
+	errorHandler   alog.ErrorHandler
 }
 
 // Option sets an option for the emitter.
@@ -66,6 +74,13 @@ func WithTimestampField(field string) Option {
 	return func(o *Options) { o.timestampField = field }
 }
 
+// WithLevelField overrides the JSON field used for the level of the entry.
+//
+// If this option is not specified, DefaultLevelField will be used.
+func WithLevelField(field string) Option {
+	return func(o *Options) { o.levelField = field }
+}
+
 // WithFile collects call information on each log line, like the log
 // package's Llongfile flag.
 //
@@ -103,3 +118,12 @@ func WithMessageField(field string) Option {
 func WithWriter(w io.Writer) Option {
 	return func(o *Options) { o.writer = w }
 }
+
+// WithErrorHandler sets the handler for errors writing entries or marshalling
+// structured tags.
+//
+// If this option is not specified, the handler set on the Logger with
+// alog.WithErrorHandler is used, if any.
+func WithErrorHandler(h alog.ErrorHandler) Option {
+	return func(o *Options) { o.errorHandler = h }
+}
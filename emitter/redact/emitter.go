@@ -0,0 +1,336 @@
+// Package redact provides an alog.Emitter that removes or obscures sensitive
+// values before they reach another emitter.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+)
+
+// Emitter returns an emitter that applies rules to each entry before passing it
+// on to next.
+//
+// For each tag, the first key rule that matches its key is applied. Tags that
+// no key rule matches have every value rule applied to their value instead.
+// Messages have every value rule applied. Field rules are applied to the
+// structured tag values that no key rule matched; if more than one is given,
+// only the first is used.
+//
+// If the context carries a request set with gkelog.WithRequest, it is
+// replaced with a copy that has had the same rules applied to its headers and
+// query parameters, so gkelog doesn't write out credentials such as API keys
+// and tokens. gkelog leaves out the Authorization and Cookie headers itself.
+//
+// The entry passed to next is a copy; the original entry is not modified.
+func Emitter(next alog.Emitter, rules ...Rule) alog.Emitter {
+	var keys, values []*Rule
+	var field *Rule
+	for i := range rules {
+		r := &rules[i]
+		switch r.kind {
+		case keyRule:
+			keys = append(keys, r)
+		case valueRule:
+			values = append(values, r)
+		case fieldRule:
+			if field == nil {
+				field = r
+			}
+		}
+	}
+	rd := &redactor{keys: keys, values: values, field: field}
+
+	return alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		entry := *e
+		entry.Msg = rd.text(entry.Msg)
+		entry.Tags = rd.tags(entry.Tags)
+		entry.STags = rd.sTags(entry.STags)
+
+		if req := gkelog.RequestFromContext(ctx); req != nil {
+			ctx = gkelog.ReplaceRequest(ctx, rd.request(req))
+		}
+
+		next.Emit(ctx, &entry)
+	})
+}
+
+type redactor struct {
+	keys   []*Rule
+	values []*Rule
+	field  *Rule
+}
+
+// keyRule returns the first key rule matching key, or nil if there isn't one.
+func (rd *redactor) keyRule(key string) *Rule {
+	for _, r := range rd.keys {
+		if r.matchKey(key) {
+			return r
+		}
+	}
+	return nil
+}
+
+// text applies the value rules to s.
+func (rd *redactor) text(s string) string {
+	for _, r := range rd.values {
+		s = r.replace(s)
+	}
+	return s
+}
+
+// value applies the key rule for key to v if there is one, or the value rules
+// otherwise.
+func (rd *redactor) value(key, v string) (string, bool) {
+	if r := rd.keyRule(key); r != nil {
+		return r.action(v)
+	}
+	return rd.text(v), true
+}
+
+func (rd *redactor) tags(tags [][2]string) [][2]string {
+	if len(tags) == 0 {
+		return tags
+	}
+	out := make([][2]string, 0, len(tags))
+	for _, t := range tags {
+		v, ok := rd.value(t[0], t[1])
+		if ok {
+			out = append(out, [2]string{t[0], v})
+		}
+	}
+	return out
+}
+
+func (rd *redactor) sTags(tags []alog.STag) []alog.STag {
+	if len(tags) == 0 {
+		return tags
+	}
+	out := make([]alog.STag, 0, len(tags))
+	for _, t := range tags {
+		if r := rd.keyRule(t.Key); r != nil {
+			v, ok := r.action(fmt.Sprint(t.Val))
+			if ok {
+				out = append(out, alog.STag{Key: t.Key, Val: v})
+			}
+			continue
+		}
+		switch v := t.Val.(type) {
+		case string:
+			t.Val = rd.text(v)
+		default:
+			if rd.field != nil {
+				t.Val = rd.fields(v)
+			}
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// fields returns a copy of v with the field rule applied to the tagged fields
+// it holds. Values whose type can't hold any are returned as is.
+func (rd *redactor) fields(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || !hasRedactFields(rv.Type()) {
+		return v
+	}
+	c := &fieldCopier{rule: rd.field, seen: make(map[visit]reflect.Value)}
+	return c.copy(rv).Interface()
+}
+
+// visit identifies a pointer, slice or map that has already been copied.
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// fieldCopier makes copies of values with the field rule applied. Pointers,
+// slices and maps that are reached more than once, such as those in cyclic
+// values, are copied once, and the copies refer to each other the way the
+// originals do.
+type fieldCopier struct {
+	rule *Rule
+	seen map[visit]reflect.Value
+}
+
+// copy returns a copy of v with the field rule applied, or v itself if its
+// type can't hold any tagged fields.
+func (c *fieldCopier) copy(v reflect.Value) reflect.Value {
+	if !hasRedactFields(v.Type()) {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		c.structFields(cp)
+		return cp
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		k := visit{v.Pointer(), 0, v.Type()}
+		if cp, ok := c.seen[k]; ok {
+			return cp
+		}
+		cp := reflect.New(v.Type().Elem())
+		c.seen[k] = cp
+		cp.Elem().Set(c.copy(v.Elem()))
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		k := visit{v.Pointer(), v.Len(), v.Type()}
+		if cp, ok := c.seen[k]; ok {
+			return cp
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		c.seen[k] = cp
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		k := visit{v.Pointer(), 0, v.Type()}
+		if cp, ok := c.seen[k]; ok {
+			return cp
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.seen[k] = cp
+		for it := v.MapRange(); it.Next(); {
+			cp.SetMapIndex(it.Key(), c.copy(it.Value()))
+		}
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(c.copy(v.Elem()))
+		return cp
+	}
+	return v
+}
+
+// structFields applies the field rule to the tagged fields of the addressable
+// struct v, and copies the values its other fields refer to.
+func (c *fieldCopier) structFields(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+		if t.Field(i).Tag.Get("alog") == "redact" {
+			if f.Kind() == reflect.String {
+				s, ok := c.rule.action(f.String())
+				if ok {
+					f.SetString(s)
+					continue
+				}
+			}
+			f.Set(reflect.Zero(f.Type()))
+			continue
+		}
+		f.Set(c.copy(f))
+	}
+}
+
+// redactTypes caches hasRedactFields.
+var redactTypes sync.Map // map[reflect.Type]bool
+
+// hasRedactFields reports whether values of type t can hold exported struct
+// fields tagged `alog:"redact"`. Interfaces may hold anything.
+func hasRedactFields(t reflect.Type) bool {
+	if has, ok := redactTypes.Load(t); ok {
+		return has.(bool)
+	}
+	has := scanType(t, make(map[reflect.Type]bool))
+	redactTypes.Store(t, has)
+	return has
+}
+
+func scanType(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return scanType(t.Elem(), visiting)
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			if f.Tag.Get("alog") == "redact" || scanType(f.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// request returns a shallow copy of req with the rules applied to its headers
+// and query parameters.
+func (rd *redactor) request(req *http.Request) *http.Request {
+	r := new(http.Request)
+	*r = *req
+
+	r.Header = make(http.Header, len(req.Header))
+	for name, vs := range req.Header {
+		for _, v := range vs {
+			if v, ok := rd.value(name, v); ok {
+				r.Header[name] = append(r.Header[name], v)
+			}
+		}
+	}
+
+	if req.URL != nil && req.URL.RawQuery != "" {
+		query := req.URL.Query()
+		changed := false
+		for name, vs := range query {
+			redacted := vs[:0:0]
+			for _, v := range vs {
+				rv, ok := rd.value(name, v)
+				if ok {
+					redacted = append(redacted, rv)
+				}
+				changed = changed || !ok || rv != v
+			}
+			if len(redacted) == 0 {
+				delete(query, name)
+				continue
+			}
+			query[name] = redacted
+		}
+		// Re-encoding sorts the parameters, so leave the URL alone unless
+		// something was redacted.
+		if changed {
+			u := *req.URL
+			u.RawQuery = query.Encode()
+			r.URL = &u
+		}
+	}
+	return r
+}
@@ -0,0 +1,164 @@
+package redact
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+	"github.com/vimeo/alog/v3/emitter/jsonlog"
+)
+
+var zeroTimeOpt = alog.OverrideTimestamp(func() time.Time { return time.Time{} })
+
+func TestTags(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(jsonlog.Emitter(b, jsonlog.WithDateFormat("")),
+		KeyRule("password", Drop()),
+		KeyRule("*_token", Mask("***")),
+		KeyRule("user", HMAC([]byte("secret"))),
+		ValueRule(regexp.MustCompile(`\d{4}-\d{4}-\d{4}-\d{4}`), Mask("<card>")),
+	)))
+
+	ctx := alog.AddTags(context.Background(),
+		"Password", "hunter2",
+		"api_token", "abc",
+		"user", "alice",
+		"note", "paid with 1234-5678-9012-3456")
+	ctx = alog.AddStructuredTags(ctx, alog.STag{Key: "refresh_token", Val: 42})
+	l.Print(ctx, "charged 1234-5678-9012-3456")
+
+	want := `{"tags":{"api_token":"***", "user":"hmac:4360c67bc81025114044578d7c4e8e0f", "note":"paid with <card>"}, "sTags":{"refresh_token":"***"}, "message":"charged <card>"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHMACCorrelates(t *testing.T) {
+	h := HMAC([]byte("secret"))
+	a, _ := h("alice")
+	b, _ := h("alice")
+	c, _ := h("bob")
+	if a != b || a == c {
+		t.Errorf("got %q, %q, %q", a, b, c)
+	}
+}
+
+type credentials struct {
+	User     string `json:"user"`
+	Password string `json:"password" alog:"redact"`
+	PIN      int    `json:"pin" alog:"redact"`
+	Inner    struct {
+		Secret string `json:"secret" alog:"redact"`
+	} `json:"inner"`
+}
+
+func TestFields(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(jsonlog.Emitter(b, jsonlog.WithDateFormat("")),
+		FieldRule(Mask("***")),
+	)))
+
+	creds := credentials{User: "alice", Password: "hunter2", PIN: 1234}
+	creds.Inner.Secret = "shh"
+	ctx := alog.AddStructuredTags(context.Background(),
+		alog.STag{Key: "value", Val: creds},
+		alog.STag{Key: "pointer", Val: &creds})
+	l.Print(ctx, "test")
+
+	want := `{"sTags":{"value":{"user":"alice","password":"***","pin":0,"inner":{"secret":"***"}}, "pointer":{"user":"alice","password":"***","pin":0,"inner":{"secret":"***"}}}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if creds.Password != "hunter2" || creds.Inner.Secret != "shh" {
+		t.Errorf("original value modified: %+v", creds)
+	}
+}
+
+type node struct {
+	Name   string `json:"name"`
+	Secret string `json:"-" alog:"redact"`
+	Next   *node  `json:"-"`
+}
+
+func TestFieldsContainers(t *testing.T) {
+	var got []interface{}
+	l := alog.New(alog.WithEmitter(Emitter(alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		for _, t := range e.STags {
+			got = append(got, t.Val)
+		}
+	}), FieldRule(Mask("***")))))
+
+	creds := credentials{User: "alice", Password: "hunter2"}
+	cycle := &node{Name: "a", Secret: "s1"}
+	cycle.Next = &node{Name: "b", Secret: "s2", Next: cycle}
+	ctx := alog.AddStructuredTags(context.Background(),
+		alog.STag{Key: "slice", Val: []credentials{creds}},
+		alog.STag{Key: "array", Val: [1]*credentials{&creds}},
+		alog.STag{Key: "map", Val: map[string]interface{}{"creds": creds}},
+		alog.STag{Key: "cycle", Val: cycle})
+	l.Print(ctx, "test")
+
+	if p := got[0].([]credentials)[0].Password; p != "***" {
+		t.Errorf("slice: password %q", p)
+	}
+	if p := got[1].([1]*credentials)[0].Password; p != "***" {
+		t.Errorf("array: password %q", p)
+	}
+	if p := got[2].(map[string]interface{})["creds"].(credentials).Password; p != "***" {
+		t.Errorf("map: password %q", p)
+	}
+	n := got[3].(*node)
+	if n.Secret != "***" || n.Next.Secret != "***" || n.Next.Next != n {
+		t.Errorf("cycle: got %+v -> %+v", n, n.Next)
+	}
+	if creds.Password != "hunter2" || cycle.Secret != "s1" || cycle.Next.Secret != "s2" {
+		t.Error("original values modified")
+	}
+}
+
+func TestRequest(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(gkelog.Emitter(gkelog.WithWriter(b)),
+		KeyRule("authorization", Mask("***")),
+		KeyRule("x-api-key", Mask("***")),
+		KeyRule("token", Drop()),
+	)), zeroTimeOpt)
+
+	req := httptest.NewRequest(http.MethodGet, "/test?token=abc&q=1", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("X-Api-Key", "abc")
+	req.Header.Set("Dnt", "1")
+	l.Print(gkelog.WithRequest(context.Background(), req), "test")
+
+	want := `{"time":"0001-01-01T00:00:00Z", "httpRequest":{"requestMethod":"GET", "requestUrl":"/test?q=1"}, "httpHeaders":{"Dnt":["1"], "X-Api-Key":["***"]}, "httpQuery":{"q":["1"]}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer abc" {
+		t.Errorf("original request modified: %q", got)
+	}
+}
+
+func TestRequestTrace(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(gkelog.Emitter(gkelog.WithWriter(b)), KeyRule("dnt", Drop()))), zeroTimeOpt)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("X-Cloud-Trace-Context", "a2fbf27a2ed90077e0d4af0e40a241f9/12345;o=1")
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	l.Print(gkelog.WithRequest(context.Background(), req, gkelog.TraceParent), "test")
+
+	// The trace read with the given headers is kept, not read again with
+	// the default ones.
+	want := `"logging.googleapis.com/trace":"4bf92f3577b34da6a3ce929d0e0e4736"`
+	if got := b.String(); !strings.Contains(got, want) {
+		t.Errorf("got:\n%s\nwant it to contain:\n%s", got, want)
+	}
+}
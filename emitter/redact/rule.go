@@ -0,0 +1,99 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Action decides what happens to a value that matched a Rule. It returns the
+// replacement value, or false if the value should be dropped altogether.
+type Action func(value string) (string, bool)
+
+// Drop returns an Action that removes the value. Dropped tags are removed from
+// the entry; dropped parts of messages and tag values are removed from the
+// text.
+func Drop() Action {
+	return func(string) (string, bool) { return "", false }
+}
+
+// Mask returns an Action that replaces the value with replacement.
+func Mask(replacement string) Action {
+	return func(string) (string, bool) { return replacement, true }
+}
+
+// HMAC returns an Action that replaces the value with a keyed hash of it, so
+// redacted values can still be correlated across entries without being
+// revealed. The hash is the first 16 bytes of the HMAC-SHA256 of the value,
+// hex encoded, and prefixed with "hmac:".
+func HMAC(key []byte) Action {
+	return func(value string) (string, bool) {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(value))
+		return "hmac:" + hex.EncodeToString(h.Sum(nil)[:16]), true
+	}
+}
+
+type ruleKind uint8
+
+const (
+	keyRule ruleKind = iota
+	valueRule
+	fieldRule
+)
+
+// Rule selects values to redact, and the Action applied to them.
+type Rule struct {
+	kind    ruleKind
+	pattern string
+	re      *regexp.Regexp
+	action  Action
+}
+
+// KeyRule returns a Rule that applies a to every value whose key matches
+// pattern, using the syntax of path.Match. Keys are compared without regard to
+// case.
+//
+// It applies to the keys of tags and structured tags, and to the names of the
+// headers and query parameters of a request set with gkelog.WithRequest.
+// Structured tag values are converted to a string with fmt.Sprint before a is
+// applied.
+func KeyRule(pattern string, a Action) Rule {
+	return Rule{kind: keyRule, pattern: strings.ToLower(pattern), action: a}
+}
+
+// ValueRule returns a Rule that applies a to every match of re in messages,
+// tag values, string structured tag values, and header and query parameter
+// values.
+func ValueRule(re *regexp.Regexp, a Action) Rule {
+	return Rule{kind: valueRule, re: re, action: a}
+}
+
+// FieldRule returns a Rule that applies a to the fields of structured tag
+// values that are marked with an `alog:"redact"` struct tag. It looks through
+// the exported fields of structs, and through pointers, slices, arrays, maps
+// and interfaces, so cyclic values are safe. Unexported fields are left as
+// they are, since they can't be set.
+//
+// String fields are set to the result of a. Fields of other types are set to
+// their zero value, as are string fields that a drops.
+func FieldRule(a Action) Rule {
+	return Rule{kind: fieldRule, action: a}
+}
+
+// matchKey reports whether key matches the pattern of a key rule.
+func (r *Rule) matchKey(key string) bool {
+	ok, _ := path.Match(r.pattern, strings.ToLower(key))
+	return ok
+}
+
+// replace applies the action of a value rule to every match in s.
+func (r *Rule) replace(s string) string {
+	return r.re.ReplaceAllStringFunc(s, func(m string) string {
+		v, _ := r.action(m)
+		return v
+	})
+}
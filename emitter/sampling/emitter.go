@@ -0,0 +1,177 @@
+// Package sampling provides an alog.Emitter that limits how often similar
+// entries are emitted, and reports how many were suppressed.
+package sampling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// SuppressedKey is the key of the structured tag holding the number of
+// suppressed entries in a summary entry.
+const SuppressedKey = "suppressed"
+
+// ErrClosed is returned by Close if the Emitter has already been closed.
+var ErrClosed = errors.New("sampling: emitter closed")
+
+type pending struct {
+	ctx context.Context
+	e   alog.Entry
+}
+
+// Emitter passes entries on to another emitter according to a Policy.
+//
+// At the end of each window, for every key that had entries suppressed, it
+// emits a summary entry based on the latest suppressed one, with the message
+// replaced and a SuppressedKey structured tag holding the count.
+//
+// Close must be called to stop the background goroutine that ends the
+// windows.
+type Emitter struct {
+	next   alog.Emitter
+	policy Policy
+	o      Options
+	now    func() time.Time
+
+	mu   sync.Mutex
+	keys map[string]*keyState
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	stopped   chan struct{}
+}
+
+// New returns an Emitter that emits the entries allowed by policy through
+// next.
+func New(next alog.Emitter, policy Policy, opt ...Option) *Emitter {
+	o := Options{
+		interval: DefaultInterval,
+		key:      CallerKey,
+	}
+	for _, option := range opt {
+		option(&o)
+	}
+	if o.interval <= 0 {
+		o.interval = DefaultInterval
+	}
+
+	s := &Emitter{
+		next:    next,
+		policy:  policy,
+		o:       o,
+		now:     time.Now,
+		keys:    make(map[string]*keyState),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Emit implements alog.Emitter.
+func (s *Emitter) Emit(ctx context.Context, e *alog.Entry) {
+	if s.o.exempt && e.Level >= s.o.minLevel {
+		s.next.Emit(ctx, e)
+		return
+	}
+
+	key := s.o.key(e)
+	s.mu.Lock()
+	st, ok := s.keys[key]
+	if !ok {
+		st = &keyState{}
+		s.keys[key] = st
+	}
+	st.Seen++
+	allowed := s.policy(&st.KeyState, s.now())
+	if !allowed {
+		st.suppressed++
+		st.latest = pending{ctx: context.WithoutCancel(ctx), e: *e}
+	}
+	s.mu.Unlock()
+
+	if allowed {
+		s.next.Emit(ctx, e)
+	}
+}
+
+// Close emits the summaries for the current window and stops the background
+// goroutine.
+func (s *Emitter) Close() error {
+	err := ErrClosed
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.stopped
+		s.endWindow()
+		err = nil
+	})
+	return err
+}
+
+func (s *Emitter) run() {
+	defer close(s.stopped)
+	t := time.NewTicker(s.o.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.endWindow()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// summary is the summary entry of a key.
+type summary struct {
+	key string
+	pending
+}
+
+// endWindow resets the counts of every key, forgets the keys that had no
+// entries, and emits a summary for the keys that had entries suppressed, in
+// order of their keys.
+func (s *Emitter) endWindow() {
+	var summaries []summary
+	s.mu.Lock()
+	for key, st := range s.keys {
+		if st.Seen == 0 {
+			delete(s.keys, key)
+			continue
+		}
+		if st.suppressed > 0 {
+			p := st.latest
+			p.e.Msg = fmt.Sprintf("suppressed %d entries like: %s", st.suppressed, p.e.Msg)
+			p.e.STags = replaceSTag(p.e.STags, alog.STag{Key: SuppressedKey, Val: st.suppressed})
+			summaries = append(summaries, summary{key: key, pending: p})
+		}
+		st.Seen, st.suppressed = 0, 0
+		st.latest = pending{}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].key < summaries[j].key })
+	now := s.now()
+	for _, p := range summaries {
+		p.e.Time = now
+		s.next.Emit(p.ctx, &p.e)
+	}
+}
+
+// replaceSTag returns a copy of tags with t added at the end, in place of any
+// structured tag with the same key, since keys must be unique.
+func replaceSTag(tags []alog.STag, t alog.STag) []alog.STag {
+	out := make([]alog.STag, 0, len(tags)+1)
+	for _, old := range tags {
+		if old.Key != t.Key {
+			out = append(out, old)
+		}
+	}
+	return append(out, t)
+}
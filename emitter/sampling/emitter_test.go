@@ -0,0 +1,210 @@
+package sampling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+func bufEmitter(b *bytes.Buffer) alog.Emitter {
+	return alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		fmt.Fprintf(b, "%s %v\n", e.Msg, e.STags)
+	})
+}
+
+func TestFirstThenEvery(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), FirstThenEvery(2, 3), WithInterval(time.Hour), WithKey(MessageKey))
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := context.Background()
+	for i := 0; i < 9; i++ {
+		l.Print(ctx, "hot")
+	}
+	l.Print(ctx, "cold")
+	s.Close()
+
+	want := "hot []\nhot []\nhot []\nhot []\ncold []\nsuppressed 5 entries like: hot [{suppressed 5}]\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), TokenBucket(1, 2), WithInterval(time.Hour), WithKey(MessageKey))
+	now := time.Unix(0, 0)
+	s.now = func() time.Time { return now }
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		l.Printf(ctx, "burst")
+	}
+	now = now.Add(1500 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		l.Printf(ctx, "burst")
+	}
+	s.Close()
+
+	want := "burst []\nburst []\nburst []\nsuppressed 4 entries like: burst [{suppressed 4}]\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCallerKey(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), FirstThenEvery(1, 0), WithInterval(time.Hour))
+	defer s.Close()
+	l := alog.New(alog.WithEmitter(s), alog.WithCaller())
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		l.Printf(ctx, "a%d", i)
+		l.Printf(ctx, "b%d", i)
+	}
+
+	if got, want := b.String(), "a0 []\nb0 []\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCallerKeyWithoutCaller(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), FirstThenEvery(1, 0), WithInterval(time.Hour))
+	defer s.Close()
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		l.Print(ctx, "a")
+		l.Print(ctx, "b")
+	}
+
+	if got, want := b.String(), "a []\nb []\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSummaryOrder(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), FirstThenEvery(0, 0), WithInterval(time.Hour), WithKey(MessageKey))
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := context.Background()
+	for _, msg := range []string{"d", "b", "e", "a", "c"} {
+		l.Print(ctx, msg)
+	}
+	s.Close()
+
+	want := ""
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		want += "suppressed 1 entries like: " + msg + " [{suppressed 1}]\n"
+	}
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCustomPolicy(t *testing.T) {
+	b := &bytes.Buffer{}
+	// Emit every other entry, across windows.
+	everyOther := func(st *KeyState, now time.Time) bool {
+		n, _ := st.Data.(int)
+		st.Data = n + 1
+		return n%2 == 0
+	}
+	s := New(bufEmitter(b), everyOther, WithInterval(time.Hour), WithKey(MessageKey))
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := context.Background()
+	l.Print(ctx, "x")
+	l.Print(ctx, "x")
+	l.Print(ctx, "x")
+	s.endWindow()
+	l.Print(ctx, "x")
+	l.Print(ctx, "x")
+	s.Close()
+
+	want := "x []\nx []\nsuppressed 1 entries like: x [{suppressed 1}]\nx []\nsuppressed 1 entries like: x [{suppressed 1}]\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestExemptLevel(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), FirstThenEvery(0, 0), WithInterval(time.Hour), WithExemptLevel(alog.LevelError))
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := context.Background()
+	l.Print(alog.WithLevel(ctx, alog.LevelWarning), "dropped")
+	l.Print(alog.WithLevel(ctx, alog.LevelError), "kept")
+	l.Print(alog.WithLevel(ctx, alog.LevelCritical), "kept")
+	s.Close()
+
+	want := "kept []\nkept []\nsuppressed 1 entries like: dropped [{suppressed 1}]\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), FirstThenEvery(1, 0), WithInterval(time.Hour), WithKey(MessageKey))
+	defer s.Close()
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := context.Background()
+	l.Print(ctx, "x")
+	l.Print(ctx, "x")
+	s.endWindow()
+	l.Print(ctx, "x")
+	s.endWindow()
+	s.endWindow()
+	if got := len(s.keys); got != 0 {
+		t.Errorf("idle keys kept: %d", got)
+	}
+
+	want := "x []\nsuppressed 1 entries like: x [{suppressed 1}]\nx []\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestClose(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		s := New(alog.EmitterFunc(func(context.Context, *alog.Entry) {}), FirstThenEvery(1, 1), WithInterval(d))
+		if s.o.interval != DefaultInterval {
+			t.Errorf("WithInterval(%v): interval %v, want %v", d, s.o.interval, DefaultInterval)
+		}
+		if err := s.Close(); err != nil {
+			t.Errorf("got: %v, want: nil", err)
+		}
+		if err := s.Close(); err != ErrClosed {
+			t.Errorf("got: %v, want: %v", err, ErrClosed)
+		}
+	}
+}
+
+func TestSummaryKey(t *testing.T) {
+	b := &bytes.Buffer{}
+	s := New(bufEmitter(b), FirstThenEvery(1, 10), WithInterval(time.Hour), WithKey(MessageKey))
+	l := alog.New(alog.WithEmitter(s))
+
+	ctx := alog.AddStructuredTags(context.Background(), alog.STag{Key: SuppressedKey, Val: "no"}, alog.STag{Key: "n", Val: 1})
+	for i := 0; i < 3; i++ {
+		l.Print(ctx, "hot")
+	}
+	s.Close()
+
+	want := "hot [{suppressed no} {n 1}]\nsuppressed 2 entries like: hot [{n 1} {suppressed 2}]\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package sampling
+
+import (
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// DefaultInterval is the length of the sampling window if WithInterval is not
+// specified.
+const DefaultInterval = time.Second
+
+// Options holds option values.
+type Options struct {
+	interval time.Duration
+	key      KeyFunc
+	exempt   bool
+	minLevel alog.Level
+}
+
+// Option sets an option for the emitter.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithInterval sets the length of the sampling window. Counts are reset, and
+// summaries of the suppressed entries are emitted, at the end of each window.
+//
+// If this option is not specified, or d is not positive, DefaultInterval is
+// used.
+func WithInterval(d time.Duration) Option {
+	return func(o *Options) { o.interval = d }
+}
+
+// WithKey sets how entries are grouped for sampling.
+//
+// If this option is not specified, CallerKey is used.
+func WithKey(f KeyFunc) Option {
+	return func(o *Options) { o.key = f }
+}
+
+// WithExemptLevel exempts entries with a level of at least min from sampling,
+// so they are always emitted and don't count against their key.
+func WithExemptLevel(min alog.Level) Option {
+	return func(o *Options) {
+		o.exempt = true
+		o.minLevel = min
+	}
+}
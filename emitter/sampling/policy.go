@@ -0,0 +1,98 @@
+package sampling
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// KeyFunc returns the key that entries are grouped by for sampling. Each key
+// is limited separately.
+type KeyFunc func(e *alog.Entry) string
+
+// CallerKey groups entries by the file and line they were logged from. That
+// requires the Logger to be created with alog.WithCaller: entries without
+// caller information are grouped by their message, as MessageKey does.
+func CallerKey(e *alog.Entry) string {
+	if e.File == "" {
+		return e.Msg
+	}
+	return e.File + ":" + strconv.Itoa(e.Line)
+}
+
+// MessageKey groups entries by their message.
+func MessageKey(e *alog.Entry) string {
+	return e.Msg
+}
+
+// KeyState is the sampling state of a single key, as seen by a Policy.
+type KeyState struct {
+	// Seen is the number of entries with the key in the current window,
+	// counting the one being decided on.
+	Seen uint64
+
+	// Data is kept for the Policy from one entry to the next, for as long
+	// as the Emitter keeps the key: keys are forgotten at the end of a
+	// window without any entries.
+	Data interface{}
+}
+
+// keyState is the state the Emitter keeps for a key.
+type keyState struct {
+	KeyState
+
+	// suppressed counts the entries suppressed in the current window.
+	suppressed uint64
+
+	// latest is the most recently suppressed entry, used as the template
+	// for the summary entry.
+	latest pending
+}
+
+// Policy decides whether an entry with the key whose state is s is emitted, at
+// time now. Policies are called with the lock of the Emitter held, so they may
+// modify s, but must not block.
+type Policy func(s *KeyState, now time.Time) bool
+
+// FirstThenEvery returns a Policy that emits the first entries of each window
+// for a key, then every thereafter-th entry after that. A thereafter of zero
+// drops every entry after the first ones.
+func FirstThenEvery(first, thereafter uint64) Policy {
+	return func(s *KeyState, now time.Time) bool {
+		if s.Seen <= first {
+			return true
+		}
+		return thereafter > 0 && (s.Seen-first)%thereafter == 0
+	}
+}
+
+// bucket is the state of a key for TokenBucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket returns a Policy that emits up to rate entries per second for a
+// key, allowing bursts of up to burst entries.
+func TokenBucket(rate float64, burst int) Policy {
+	max := float64(burst)
+	return func(s *KeyState, now time.Time) bool {
+		b, ok := s.Data.(*bucket)
+		if !ok {
+			b = &bucket{tokens: max}
+			s.Data = b
+		} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+			b.tokens += elapsed * rate
+			if b.tokens > max {
+				b.tokens = max
+			}
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return false
+		}
+		b.tokens--
+		return true
+	}
+}
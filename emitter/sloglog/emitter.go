@@ -0,0 +1,71 @@
+// Package sloglog provides an alog.Emitter that forwards entries to a
+// slog.Handler.
+package sloglog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/internal"
+)
+
+// levels maps each alog.Level to a slog level. The levels slog doesn't name
+// are spaced out the same way slog's own levels are.
+var levels = [...]slog.Level{
+	alog.LevelNone:      slog.LevelInfo,
+	alog.LevelDebug:     slog.LevelDebug,
+	alog.LevelInfo:      slog.LevelInfo,
+	alog.LevelNotice:    slog.LevelInfo + 2,
+	alog.LevelWarning:   slog.LevelWarn,
+	alog.LevelError:     slog.LevelError,
+	alog.LevelCritical:  slog.LevelError + 4,
+	alog.LevelAlert:     slog.LevelError + 8,
+	alog.LevelEmergency: slog.LevelError + 12,
+}
+
+// Emitter forwards log entries to h as slog.Records.
+//
+// Tags become string attributes and STags become attributes holding the
+// structured value. String tags take precedence over structured tags with the
+// same key. The level of the entry becomes the level of the record; entries
+// without a level are logged at slog.LevelInfo. Caller information, when
+// present, is passed on as a slog.SourceKey group with "file" and "line"
+// attributes.
+//
+// Entries are dropped if h is not enabled for their level.
+func Emitter(h slog.Handler, opt ...Option) alog.Emitter {
+	o := new(Options)
+	for _, option := range opt {
+		option(o)
+	}
+
+	return alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) {
+		level := slog.LevelInfo
+		if int(e.Level) < len(levels) {
+			level = levels[e.Level]
+		}
+		if !h.Enabled(ctx, level) {
+			return
+		}
+
+		r := slog.NewRecord(e.Time, level, e.Msg, 0)
+		if e.File != "" {
+			r.AddAttrs(slog.Group(slog.SourceKey, slog.String("file", e.File), slog.Int("line", e.Line)))
+		}
+		for _, tag := range e.Tags {
+			r.AddAttrs(slog.String(tag[0], tag[1]))
+		}
+
+		for _, tag := range e.STags {
+			if internal.HasTag(e.Tags, tag.Key) {
+				continue
+			}
+			r.AddAttrs(slog.Any(tag.Key, tag.Val))
+		}
+
+		if err := h.Handle(ctx, r); err != nil {
+			alog.ReportError(ctx, o.errorHandler, e, err)
+		}
+	})
+}
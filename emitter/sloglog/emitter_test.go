@@ -0,0 +1,72 @@
+package sloglog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/leveled"
+)
+
+var zeroTimeOpt = alog.OverrideTimestamp(func() time.Time { return time.Time{} })
+
+// Keep this function at the top of the file so that the line number doesn't change too often
+func TestCaller(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := slog.NewJSONHandler(b, &slog.HandlerOptions{ReplaceAttr: shortFile})
+	l := alog.New(alog.WithCaller(), alog.WithEmitter(Emitter(h)), zeroTimeOpt)
+
+	l.Print(context.Background(), "test")
+
+	want := `{"level":"INFO","msg":"test","source":{"file":"emitter_test.go","line":23}}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// shortFile strips the directory from the source file, which depends on where
+// the repository is checked out.
+func shortFile(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 1 && groups[0] == slog.SourceKey && a.Key == "file" {
+		a.Value = slog.StringValue(filepath.Base(a.Value.String()))
+	}
+	return a
+}
+
+func TestEmitter(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(Emitter(slog.NewJSONHandler(b, nil))), zeroTimeOpt)
+
+	ctx := alog.AddTags(context.Background(), "a", "1", "b", "2", "a", "3")
+	ctx = alog.AddStructuredTags(ctx,
+		alog.STag{Key: "s", Val: struct {
+			X int `json:"x"`
+		}{X: 1}},
+		alog.STag{Key: "b", Val: 4})
+	l.Print(ctx, "test")
+
+	want := `{"level":"INFO","msg":"test","b":"2","a":"3","s":{"x":1}}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := slog.NewTextHandler(b, &slog.HandlerOptions{Level: slog.LevelWarn})
+	l := leveled.Default(alog.New(alog.WithEmitter(Emitter(h)), zeroTimeOpt))
+
+	ctx := context.Background()
+	l.Info(ctx, "not logged")
+	l.Warning(ctx, "logged")
+	l.Critical(ctx, "logged")
+
+	want := "level=WARN msg=logged\n" + "level=ERROR+4 msg=logged\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,22 @@
+package sloglog
+
+import "github.com/vimeo/alog/v3"
+
+// Options holds option values.
+type Options struct {
+	errorHandler alog.ErrorHandler
+}
+
+// Option sets an option for the emitter.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithErrorHandler sets the handler for the errors returned by the
+// slog.Handler.
+//
+// If this option is not specified, the handler set on the Logger with
+// alog.WithErrorHandler is used, if any.
+func WithErrorHandler(h alog.ErrorHandler) Option {
+	return func(o *Options) { o.errorHandler = h }
+}
@@ -15,7 +15,7 @@ func DefaultLogger(t testing.TB, opt ...Option) *alog.Logger {
 	return alog.New(alog.WithCaller(), alog.WithEmitter(Emitter(t, opt...)))
 }
 
-// Emitter should be used for tests to log the file path,
+// Emitter should be used for tests to log the file path, level,
 // message, and tags
 func Emitter(t testing.TB, opt ...Option) alog.Emitter {
 	o := new(Options)
@@ -29,6 +29,10 @@ func Emitter(t testing.TB, opt ...Option) alog.Emitter {
 		if o.shortfile {
 			e.File = path.Base(e.File)
 		}
+		if e.Level != alog.LevelNone {
+			t.Logf("%s %s %s %v %+v", e.File, e.Level, e.Msg, e.Tags, e.STags)
+			return
+		}
 		t.Logf("%s %s %v %+v", e.File, e.Msg, e.Tags, e.STags)
 	})
 }
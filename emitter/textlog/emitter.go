@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/vimeo/alog/v3"
@@ -14,6 +15,18 @@ import (
 // Default is an alog.Emitter with some default options
 var Default = alog.New(alog.WithEmitter(Emitter(os.Stderr, WithShortFile(), WithDateFormat(time.RFC3339), WithUTC())))
 
+// levelColumns holds the upper-case name of each level, padded to the width of
+// the longest one so messages line up.
+var levelColumns = func() []string {
+	cols := make([]string, alog.LevelEmergency+1)
+	width := len(alog.LevelEmergency.String())
+	for l := range cols {
+		name := strings.ToUpper(alog.Level(l).String())
+		cols[l] = name + strings.Repeat(" ", width-len(name)+1)
+	}
+	return cols
+}()
+
 // Emitter emits log messages as plain text.
 //
 // Logs are output to w. The format is determined by l.
@@ -37,6 +50,9 @@ func Emitter(w io.Writer, opt ...Option) alog.Emitter {
 			m.WriteString(e.Time.Format(o.datefmt))
 			m.WriteByte(' ')
 		}
+		if e.Level != alog.LevelNone && int(e.Level) < len(levelColumns) {
+			m.WriteString(levelColumns[e.Level])
+		}
 		if o.flags&fileFlag != 0 && e.File != "" {
 			file := e.File
 			line := uint(e.Line)
@@ -81,8 +97,17 @@ func Emitter(w io.Writer, opt ...Option) alog.Emitter {
 		if m.Bytes()[m.Len()-1] != '\n' {
 			m.WriteByte('\n')
 		}
-		// Writer error is swallowed, because checking errors on writing log
-		// lines is my personal conception of hell.
-		wOut.Write(m.Bytes())
+		for _, f := range e.Stack {
+			m.WriteByte('\t')
+			m.WriteString(f.Function)
+			m.WriteString("\n\t\t")
+			m.WriteString(f.File)
+			m.WriteByte(':')
+			internal.Itoa(m, uint(f.Line))
+			m.WriteByte('\n')
+		}
+		if _, err := wOut.Write(m.Bytes()); err != nil {
+			alog.ReportError(ctx, o.errorHandler, e, err)
+		}
 	})
 }
@@ -26,3 +26,33 @@ func ExampleEmitter() {
 	// Output:
 	// 0001-01-01T00:00:00Z emitter_test.go:25: [allthese=tags] [structured={X:1}] test
 }
+
+func ExampleEmitter_levels() {
+	ctx := context.Background()
+	l := alog.New(alog.WithEmitter(Emitter(os.Stdout)))
+
+	l.Print(alog.WithLevel(ctx, alog.LevelInfo), "started")
+	l.Print(alog.WithLevel(ctx, alog.LevelEmergency), "on fire")
+	l.Print(ctx, "no level")
+	// Output:
+	// INFO      started
+	// EMERGENCY on fire
+	// no level
+}
+
+func ExampleEmitter_stack() {
+	e := &alog.Entry{
+		Msg: "test",
+		Stack: []alog.Frame{
+			{Function: "main.f", File: "/src/main.go", Line: 12},
+			{Function: "main.main", File: "/src/main.go", Line: 5},
+		},
+	}
+	Emitter(os.Stdout).Emit(context.Background(), e)
+	// Output:
+	// test
+	// 	main.f
+	// 		/src/main.go:12
+	// 	main.main
+	// 		/src/main.go:5
+}
@@ -1,5 +1,7 @@
 package textlog
 
+import "github.com/vimeo/alog/v3"
+
 const (
 	fileFlag = 1 << iota
 	shortfileFlag
@@ -9,9 +11,10 @@ const (
 
 // Options holds option values.
 type Options struct {
-	prefix  string
-	datefmt string
-	flags   uint
+	prefix       string
+	datefmt      string
+	flags        uint
+	errorHandler alog.ErrorHandler
 }
 
 // Option sets an option for the emitter.
@@ -55,3 +58,11 @@ func WithShortFile() Option {
 func WithUTC() Option {
 	return func(o *Options) { o.flags |= utcFlag }
 }
+
+// WithErrorHandler sets the handler for errors writing entries.
+//
+// If this option is not specified, the handler set on the Logger with
+// alog.WithErrorHandler is used, if any.
+func WithErrorHandler(h alog.ErrorHandler) Option {
+	return func(o *Options) { o.errorHandler = h }
+}
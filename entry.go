@@ -3,11 +3,31 @@ package alog
 import "time"
 
 // Entry is the struct passed to user-supplied formatters.
+//
+// The keys of Tags are unique, as are the keys of STags. A tag and a structured
+// tag may share a key, in which case the bundled emitters only write the tag.
+//
+// An Entry passed to an Emitter is only valid until Emit returns; see Emitter.
 type Entry struct {
 	Time  time.Time
+	Level Level
 	Tags  [][2]string
 	STags []STag
 	File  string
 	Line  int
 	Msg   string
+
+	// LazyTags are tags whose values haven't been computed yet. The Logger
+	// resolves them before passing the entry to its emitter, so emitters
+	// only see them on entries built by other code, which should call
+	// Resolve first.
+	LazyTags []LazyTag
+
+	// Stack is the stack trace of the call that logged the entry, starting
+	// with the caller, if one was captured. See WithStack and CaptureStack.
+	Stack []Frame
+
+	// Goroutine is the ID of the goroutine that logged the entry, set along
+	// with Stack.
+	Goroutine uint64
 }
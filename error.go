@@ -0,0 +1,79 @@
+package alog
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxErrorDepth limits how deep an error chain is followed by NewError, in
+// case of a cycle.
+const maxErrorDepth = 32
+
+// FieldsError is an optional interface for errors that carry structured data
+// to be logged along with their message.
+type FieldsError interface {
+	error
+	LogFields() map[string]interface{}
+}
+
+// Error is a structured description of an error, meant to be logged as the
+// value of an STag. Unlike most errors, it marshals into JSON with its
+// message.
+type Error struct {
+	// Message is the result of the Error method.
+	Message string `json:"message"`
+	// Type is the concrete type of the error, like "*fs.PathError".
+	Type string `json:"type"`
+	// Fields holds the fields of errors that implement FieldsError.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+	// Wrapped describes the errors wrapped by this one: the result of
+	// errors.Unwrap, or all the errors joined by errors.Join.
+	Wrapped []*Error `json:"wrapped,omitempty"`
+}
+
+// NewError describes err and the chain of errors it wraps. It returns nil if
+// err is nil.
+func NewError(err error) *Error {
+	return newError(err, 0)
+}
+
+func newError(err error, depth int) *Error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+	}
+	if f, ok := err.(FieldsError); ok {
+		e.Fields = f.LogFields()
+	}
+	if depth >= maxErrorDepth {
+		return e
+	}
+	switch u := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, w := range u.Unwrap() {
+			if we := newError(w, depth+1); we != nil {
+				e.Wrapped = append(e.Wrapped, we)
+			}
+		}
+	default:
+		if we := newError(errors.Unwrap(err), depth+1); we != nil {
+			e.Wrapped = []*Error{we}
+		}
+	}
+	return e
+}
+
+// String returns the message of the error, which is how the textlog emitter
+// shows it.
+func (e *Error) String() string {
+	return e.Message
+}
+
+// ErrorTag returns an STag describing err with NewError, so the message, type
+// and chain of wrapped errors are all logged.
+func ErrorTag(key string, err error) STag {
+	return STag{Key: key, Val: NewError(err)}
+}
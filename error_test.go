@@ -0,0 +1,51 @@
+package alog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+type codeError struct {
+	code int
+}
+
+func (c *codeError) Error() string {
+	return fmt.Sprintf("code %d", c.code)
+}
+
+func (c *codeError) LogFields() map[string]interface{} {
+	return map[string]interface{}{"code": c.code}
+}
+
+func TestErrorTag(t *testing.T) {
+	t.Parallel()
+	pathErr := &fs.PathError{Op: "open", Path: "/x", Err: fs.ErrNotExist}
+	err := fmt.Errorf("loading: %w", errors.Join(pathErr, &codeError{code: 7}))
+
+	tag := ErrorTag("err", err)
+	got, marshalErr := json.Marshal(tag.Val)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+
+	want := `{"message":"loading: open /x: file does not exist\ncode 7","type":"*fmt.wrapError","wrapped":[` +
+		`{"message":"open /x: file does not exist\ncode 7","type":"*errors.joinError","wrapped":[` +
+		`{"message":"open /x: file does not exist","type":"*fs.PathError","wrapped":[{"message":"file does not exist","type":"*errors.errorString"}]},` +
+		`{"message":"code 7","type":"*alog.codeError","fields":{"code":7}}]}]}`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if s := fmt.Sprintf("%+v", tag.Val); s != err.Error() {
+		t.Errorf("got %q, want %q", s, err.Error())
+	}
+}
+
+func TestNilError(t *testing.T) {
+	t.Parallel()
+	if e := NewError(nil); e != nil {
+		t.Errorf("got %+v, want nil", e)
+	}
+}
@@ -0,0 +1,65 @@
+// Package errorhandler provides ready-made alog.ErrorHandlers for reporting
+// the errors emitters run into.
+package errorhandler
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// Expvar returns an ErrorHandler that counts errors in the expvar.Int published
+// under name, publishing it if it doesn't exist yet.
+//
+// It panics if name is already published as a different type of expvar.Var.
+func Expvar(name string) alog.ErrorHandler {
+	v := counter(name)
+	return func(ctx context.Context, e *alog.Entry, err error) {
+		v.Add(1)
+	}
+}
+
+var counterMu sync.Mutex
+
+func counter(name string) *expvar.Int {
+	counterMu.Lock()
+	defer counterMu.Unlock()
+	if v := expvar.Get(name); v != nil {
+		return v.(*expvar.Int)
+	}
+	return expvar.NewInt(name)
+}
+
+// Fallback returns an ErrorHandler that writes a plain line with the error and
+// the message of the entry to w, typically os.Stderr. Writes are serialized,
+// and errors writing to w are ignored.
+func Fallback(w io.Writer) alog.ErrorHandler {
+	var mu sync.Mutex
+	return func(ctx context.Context, e *alog.Entry, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "alog: %v (message: %q)\n", err, e.Msg)
+	}
+}
+
+// Panic returns an ErrorHandler that panics with the error. It's meant for
+// tests, where a failure to log should not go unnoticed.
+func Panic() alog.ErrorHandler {
+	return func(ctx context.Context, e *alog.Entry, err error) {
+		panic(fmt.Errorf("alog: %w (message: %q)", err, e.Msg))
+	}
+}
+
+// Multi returns an ErrorHandler that passes errors to each of handlers, in
+// order.
+func Multi(handlers ...alog.ErrorHandler) alog.ErrorHandler {
+	return func(ctx context.Context, e *alog.Entry, err error) {
+		for _, h := range handlers {
+			h(ctx, e, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package errorhandler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"testing"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/textlog"
+)
+
+var errWrite = errors.New("disk full")
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestExpvar(t *testing.T) {
+	h := Expvar("alog_test_errors")
+	// The counter outlives the test when it's run more than once.
+	expvar.Get("alog_test_errors").(*expvar.Int).Set(0)
+	l := alog.New(alog.WithEmitter(textlog.Emitter(failingWriter{}, textlog.WithErrorHandler(h))))
+
+	ctx := context.Background()
+	l.Print(ctx, "a")
+	l.Print(ctx, "b")
+	// Getting the handler again uses the same counter.
+	Expvar("alog_test_errors")(ctx, &alog.Entry{}, errWrite)
+
+	if got := expvar.Get("alog_test_errors").String(); got != "3" {
+		t.Errorf("got %s, want 3", got)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(textlog.Emitter(failingWriter{})), alog.WithErrorHandler(Fallback(b)))
+
+	l.Print(context.Background(), "test")
+
+	const want = `alog: disk full (message: "test")` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	l := alog.New(alog.WithEmitter(textlog.Emitter(failingWriter{}, textlog.WithErrorHandler(Panic()))))
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, errWrite) {
+			t.Errorf("got %v, want %v", err, errWrite)
+		}
+	}()
+	l.Print(context.Background(), "test")
+}
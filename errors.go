@@ -0,0 +1,27 @@
+package alog
+
+import "context"
+
+type errorHandlerKey struct{}
+
+var errorHandlerCtxKey = errorHandlerKey{}
+
+// ErrorHandler receives the errors an emitter runs into while formatting or
+// writing an entry, such as a failed Write or a structured tag that can't be
+// marshalled.
+//
+// It's called synchronously by the emitter, so it must not log through the
+// same emitter.
+type ErrorHandler func(ctx context.Context, e *Entry, err error)
+
+// ReportError is used by emitters to report err. It calls h if it isn't nil, or
+// otherwise the ErrorHandler set on the Logger with WithErrorHandler. If
+// neither is set, err is discarded.
+func ReportError(ctx context.Context, h ErrorHandler, e *Entry, err error) {
+	if h == nil {
+		h, _ = ctx.Value(errorHandlerCtxKey).(ErrorHandler)
+	}
+	if h != nil {
+		h(ctx, e, err)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/vimeo/alog/v3
 
-go 1.12
+go 1.21
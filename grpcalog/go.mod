@@ -0,0 +1,21 @@
+module github.com/vimeo/alog/grpcalog
+
+go 1.21
+
+require (
+	github.com/vimeo/alog/v3 v3.5.0
+	google.golang.org/grpc v1.64.1
+)
+
+require (
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+// grpcalog uses alog.Level, alog.TagValue and requestid, which aren't in any
+// released alog/v3 yet. Tag the alog/v3 release that adds them first, then
+// require it here and drop this replace before tagging grpcalog.
+replace github.com/vimeo/alog/v3 => ../
@@ -0,0 +1,317 @@
+// Package grpcalog provides gRPC interceptors that set up call-scoped logging
+// and log one entry for each call once it completes.
+//
+// Server interceptors tag the context of each call with its method, its peer
+// and a request ID, which is generated, or taken from the incoming metadata
+// with WithTrustedRequestID. Client interceptors send the request ID of the
+// context, as set by requestid.WithID or a server interceptor, so it
+// follows a request across services that trust it. Other tags can be carried
+// along with WithPropagatedTags.
+//
+// The level of the completion entry is derived from the status code of the
+// call with CodeLevel, which gkelog renders as the matching severity.
+package grpcalog
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+	"github.com/vimeo/alog/v3/leveled"
+	"github.com/vimeo/alog/v3/requestid"
+)
+
+// The keys of the tags added by the interceptors. The request ID is tagged with
+// requestid.Tag.
+const (
+	MethodTag = "grpc_method"
+	PeerTag   = "grpc_peer"
+	CodeTag   = "grpc_code"
+)
+
+// The metadata keys used to carry the request ID and the propagated tags.
+// Each propagated tag is a separate value of TagsKey, with its key and value
+// query-escaped and joined by "=".
+const (
+	RequestIDKey = "x-request-id"
+	TagsKey      = "alog-tags"
+)
+
+// now is replaced by tests.
+var now = time.Now
+
+// CodeLevel is the default level of the completion entry for a call that ended
+// with code. Codes that are the caller's doing, such as NotFound and
+// InvalidArgument, are LevelInfo; those that may point to an overloaded or
+// misconfigured service are LevelWarning; and those that point to a bug are
+// LevelError.
+func CodeLevel(code codes.Code) alog.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound,
+		codes.AlreadyExists, codes.Unauthenticated:
+		return alog.LevelInfo
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange, codes.Unavailable:
+		return alog.LevelWarning
+	}
+	// Unknown, Unimplemented, Internal, DataLoss, and codes added later.
+	return alog.LevelError
+}
+
+// CodeSeverity returns the gkelog severity matching CodeLevel(code).
+func CodeSeverity(code codes.Code) string {
+	return gkelog.SeverityForLevel(CodeLevel(code))
+}
+
+func newOptions(opt []Option) *Options {
+	o := &Options{
+		codeLevel: CodeLevel,
+		newID:     requestid.New,
+	}
+	for _, option := range opt {
+		option(o)
+	}
+	return o
+}
+
+// UnaryServerInterceptor returns a server interceptor that sets up the context
+// of unary calls for logging, and logs each call with logger once the handler
+// returns. The request ID is also sent back in the response header.
+func UnaryServerInterceptor(logger *alog.Logger, opt ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opt)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := now()
+		ctx = o.serverContext(ctx, info.FullMethod)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, requestid.FromContext(ctx)))
+		resp, err := handler(ctx, req)
+		o.logCompletion(ctx, logger, info.FullMethod, err, now().Sub(start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is like UnaryServerInterceptor, for streaming calls.
+// The stream passed to the handler returns the context set up for logging.
+func StreamServerInterceptor(logger *alog.Logger, opt ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opt)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := now()
+		ctx := o.serverContext(ss.Context(), info.FullMethod)
+		ss.SetHeader(metadata.Pairs(RequestIDKey, requestid.FromContext(ctx)))
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		o.logCompletion(ctx, logger, info.FullMethod, err, now().Sub(start))
+		return err
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// serverContext returns ctx with the request ID, the method and peer tags, and
+// the propagated tags in the incoming metadata.
+func (o *Options) serverContext(ctx context.Context, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if v := md.Get(RequestIDKey); o.trustHeader && len(v) > 0 && requestid.Valid(v[0]) {
+		id = v[0]
+	}
+	if id == "" {
+		id = o.newID()
+	}
+	ctx = requestid.WithID(ctx, id)
+
+	pairs := []string{MethodTag, method}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		pairs = append(pairs, PeerTag, p.Addr.String())
+	}
+	for _, v := range md.Get(TagsKey) {
+		key, value, ok := decodeTag(v)
+		if ok && containsKey(o.propagate, key) {
+			pairs = append(pairs, key, value)
+		}
+	}
+	return alog.AddTags(ctx, pairs...)
+}
+
+// UnaryClientInterceptor returns a client interceptor that sends the request
+// ID and the propagated tags of the context of unary calls, and logs each call
+// with logger once it returns.
+func UnaryClientInterceptor(logger *alog.Logger, opt ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opt)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := now()
+		ctx = o.outgoingContext(ctx)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		o.logCompletion(clientContext(ctx, method, cc), logger, method, err, now().Sub(start))
+		return err
+	}
+}
+
+// StreamClientInterceptor is like UnaryClientInterceptor, for streaming calls.
+// A call is logged when receiving from its stream returns an error, io.EOF
+// included, or, for calls without a response stream, the response, or when
+// its context is done, whichever comes first. As with gRPC itself, a stream
+// that is neither read to the end nor canceled is never finished, so it's never
+// logged, but nothing is left running for it either.
+func StreamClientInterceptor(logger *alog.Logger, opt ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opt)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := now()
+		ctx = o.outgoingContext(ctx)
+		logCtx := clientContext(ctx, method, cc)
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			o.logCompletion(logCtx, logger, method, err, now().Sub(start))
+			return nil, err
+		}
+		s := &clientStream{
+			ClientStream:  cs,
+			serverStreams: desc.ServerStreams,
+			done: func(err error) {
+				o.logCompletion(logCtx, logger, method, err, now().Sub(start))
+			},
+		}
+		// Callers may stop reading before the end of the stream, once they
+		// have what they need, and cancel the call. AfterFunc doesn't start
+		// a goroutine until ctx is done, and finish unregisters it.
+		s.mu.Lock()
+		s.stop = context.AfterFunc(ctx, func() {
+			s.finish(status.FromContextError(ctx.Err()).Err())
+		})
+		s.mu.Unlock()
+		return s, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	done          func(error)
+
+	// mu protects finished, and stop, which unregisters the function that
+	// finishes the call when its context is done.
+	mu       sync.Mutex
+	stop     func() bool
+	finished bool
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.serverStreams:
+		s.finish(nil)
+	}
+	return err
+}
+
+// finish logs the call the first time it's called.
+func (s *clientStream) finish(err error) {
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.finished = true
+	if s.stop != nil {
+		s.stop()
+	}
+	s.mu.Unlock()
+	s.done(err)
+}
+
+// outgoingContext returns ctx with the request ID and the propagated tags
+// added to its outgoing metadata.
+func (o *Options) outgoingContext(ctx context.Context) context.Context {
+	var kv []string
+	if id := requestid.FromContext(ctx); id != "" {
+		kv = append(kv, RequestIDKey, id)
+	}
+	for _, key := range o.propagate {
+		if v, ok := alog.TagValue(ctx, key); ok {
+			kv = append(kv, TagsKey, url.QueryEscape(key)+"="+url.QueryEscape(v))
+		}
+	}
+	if len(kv) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// clientContext returns ctx with the method and peer tags for a call on cc.
+func clientContext(ctx context.Context, method string, cc *grpc.ClientConn) context.Context {
+	return alog.AddTags(ctx, MethodTag, method, PeerTag, cc.Target())
+}
+
+func (o *Options) logCompletion(ctx context.Context, logger *alog.Logger, method string, err error, latency time.Duration) {
+	s := status.Convert(err)
+	ctx = alog.AddTags(ctx, CodeTag, s.Code().String())
+	level := o.codeLevel(s.Code())
+
+	f, v := "%s %s %s", []interface{}{method, s.Code(), latency}
+	if err != nil {
+		f, v = f+": %s", append(v, s.Message())
+	}
+
+	if o.leveled == nil {
+		logger.Printf(alog.WithLevel(ctx, level), f, v...)
+		return
+	}
+	switch leveled.FromAlogLevel(level) {
+	case leveled.Debug:
+		o.leveled.Debug(ctx, f, v...)
+	case leveled.Info:
+		o.leveled.Info(ctx, f, v...)
+	case leveled.Warning:
+		o.leveled.Warning(ctx, f, v...)
+	case leveled.Error:
+		o.leveled.Error(ctx, f, v...)
+	default:
+		o.leveled.Critical(ctx, f, v...)
+	}
+}
+
+// decodeTag splits a TagsKey metadata value into its key and value.
+func decodeTag(s string) (key, value string, ok bool) {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", false
+	}
+	key, err := url.QueryUnescape(k)
+	if err != nil {
+		return "", "", false
+	}
+	value, err = url.QueryUnescape(v)
+	if err != nil {
+		return "", "", false
+	}
+	return key, value, true
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,317 @@
+package grpcalog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/textlog"
+	"github.com/vimeo/alog/v3/leveled"
+	"github.com/vimeo/alog/v3/requestid"
+)
+
+// syncBuffer is a bytes.Buffer that's safe for concurrent use, since the server
+// and client log from different goroutines.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// healthServer answers Check with NOT_FOUND for the service "missing", and
+// Watch with one response. Both log an entry with the call's context.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	logger *alog.Logger
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.logger.Print(ctx, "checking")
+	if req.Service == "missing" {
+		return nil, status.Error(codes.NotFound, "no such service")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	h.logger.Print(stream.Context(), "watching")
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+// setup starts a health server with the server interceptors on an in-process
+// listener, and returns a client using the client interceptors. The server
+// logs to srvLog and the client to cliLog.
+func setup(t *testing.T, srvOpts []Option, cliOpts []Option) (client healthpb.HealthClient, srvLog, cliLog *syncBuffer) {
+	t.Helper()
+	var tm time.Time
+	var mu sync.Mutex
+	now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		tm = tm.Add(time.Second)
+		return tm
+	}
+	t.Cleanup(func() { now = time.Now })
+
+	srvLog, cliLog = &syncBuffer{}, &syncBuffer{}
+	srvLogger := alog.New(alog.WithEmitter(textlog.Emitter(srvLog)))
+	cliLogger := alog.New(alog.WithEmitter(textlog.Emitter(cliLog)))
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(srvLogger, srvOpts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(srvLogger, srvOpts...)),
+	)
+	healthpb.RegisterHealthServer(srv, &healthServer{logger: srvLogger})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(cliLogger, cliOpts...)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(cliLogger, cliOpts...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn), srvLog, cliLog
+}
+
+// bufconn peers are "bufconn", so the tags are the same on every run.
+func TestUnary(t *testing.T) {
+	propagate := WithPropagatedTags("user", "team")
+	client, srvLog, cliLog := setup(t, []Option{propagate, WithTrustedRequestID()}, []Option{propagate})
+
+	ctx := requestid.WithID(context.Background(), "req-1")
+	ctx = alog.AddTags(ctx, "user", "a b=c", "secret", "s3cr3t")
+	var header metadata.MD
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get(RequestIDKey); len(got) != 1 || got[0] != "req-1" {
+		t.Errorf("response %s = %q", RequestIDKey, got)
+	}
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("got error %v", err)
+	}
+
+	want := `[request_id=req-1 grpc_method=/grpc.health.v1.Health/Check grpc_peer=bufconn user=a b=c] checking
+INFO      [request_id=req-1 grpc_method=/grpc.health.v1.Health/Check grpc_peer=bufconn user=a b=c grpc_code=OK] /grpc.health.v1.Health/Check OK 1s
+[request_id=req-1 grpc_method=/grpc.health.v1.Health/Check grpc_peer=bufconn user=a b=c] checking
+INFO      [request_id=req-1 grpc_method=/grpc.health.v1.Health/Check grpc_peer=bufconn user=a b=c grpc_code=NotFound] /grpc.health.v1.Health/Check NotFound 1s: no such service
+`
+	if got := srvLog.String(); got != want {
+		t.Errorf("server:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	want = `INFO      [request_id=req-1 user=a b=c secret=s3cr3t grpc_method=/grpc.health.v1.Health/Check grpc_peer=passthrough:///bufnet grpc_code=OK] /grpc.health.v1.Health/Check OK 3s
+INFO      [request_id=req-1 user=a b=c secret=s3cr3t grpc_method=/grpc.health.v1.Health/Check grpc_peer=passthrough:///bufnet grpc_code=NotFound] /grpc.health.v1.Health/Check NotFound 3s: no such service
+`
+	if got := cliLog.String(); got != want {
+		t.Errorf("client:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStream(t *testing.T) {
+	client, srvLog, cliLog := setup(t, []Option{WithRequestIDFunc(func() string { return "new" })}, nil)
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `[request_id=new grpc_method=/grpc.health.v1.Health/Watch grpc_peer=bufconn] watching
+INFO      [request_id=new grpc_method=/grpc.health.v1.Health/Watch grpc_peer=bufconn grpc_code=OK] /grpc.health.v1.Health/Watch OK 1s
+`
+	if got := srvLog.String(); got != want {
+		t.Errorf("server:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	want = `INFO      [grpc_method=/grpc.health.v1.Health/Watch grpc_peer=passthrough:///bufnet grpc_code=OK] /grpc.health.v1.Health/Watch OK 3s
+`
+	if got := cliLog.String(); got != want {
+		t.Errorf("client:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	client, _, cliLog := setup(t, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	want := `INFO      [grpc_method=/grpc.health.v1.Health/Watch grpc_peer=passthrough:///bufnet grpc_code=Canceled] /grpc.health.v1.Health/Watch Canceled 3s: context canceled
+`
+	deadline := time.Now().Add(5 * time.Second)
+	for cliLog.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := cliLog.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// eofStream is a ClientStream whose RecvMsg returns io.EOF.
+type eofStream struct {
+	grpc.ClientStream
+}
+
+func (eofStream) RecvMsg(m interface{}) error { return io.EOF }
+
+func TestStreamUnfinished(t *testing.T) {
+	b := &syncBuffer{}
+	intercept := StreamClientInterceptor(alog.New(alog.WithEmitter(textlog.Emitter(b))))
+	cc, err := grpc.NewClient("passthrough:///unused", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return eofStream{}, nil
+	}
+
+	// A stream read to the end isn't logged again when its context is
+	// canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := intercept(ctx, desc, cc, "/finished", streamer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecvMsg(nil); err != io.EOF {
+		t.Fatalf("RecvMsg = %v", err)
+	}
+	cancel()
+
+	// Streams that are never read to the end are only logged once their
+	// context is done, and nothing runs for them until then.
+	ctx, cancel = context.WithCancel(context.Background())
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		if _, err := intercept(ctx, desc, cc, "/unfinished", streamer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := runtime.NumGoroutine() - before; n > 10 {
+		t.Errorf("%d goroutines started for streams that aren't finished", n)
+	}
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for strings.Count(b.String(), "\n") < 101 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	got := b.String()
+	if strings.Count(got, "/finished OK") != 1 || strings.Count(got, "/unfinished Canceled") != 100 || strings.Count(got, "\n") != 101 {
+		t.Errorf("got:\n%s", got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	newID := WithRequestIDFunc(func() string { return "new" })
+	for _, tbl := range []struct {
+		name string
+		opts []Option
+		sent string
+		want string
+	}{
+		{"untrusted", []Option{newID}, "sent", "new"},
+		{"trusted", []Option{newID, WithTrustedRequestID()}, "sent", "sent"},
+		{"invalid", []Option{newID, WithTrustedRequestID()}, "a b", "new"},
+	} {
+		t.Run(tbl.name, func(t *testing.T) {
+			client, srvLog, _ := setup(t, tbl.opts, nil)
+			ctx := requestid.WithID(context.Background(), tbl.sent)
+			if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+				t.Fatal(err)
+			}
+			if got := srvLog.String(); !strings.HasPrefix(got, "[request_id="+tbl.want+" ") {
+				t.Errorf("got:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestLeveled(t *testing.T) {
+	b := &syncBuffer{}
+	l := leveled.Filtered(alog.New(alog.WithEmitter(textlog.Emitter(b))))
+	l.SetMinLevel(leveled.Warning)
+	client, _, _ := setup(t, []Option{WithLeveled(l), WithCodeLevel(func(code codes.Code) alog.Level {
+		if code == codes.NotFound {
+			return alog.LevelWarning
+		}
+		return CodeLevel(code)
+	})}, nil)
+
+	client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	if got := b.String(); strings.Count(got, "\n") != 1 || !strings.HasPrefix(got, "WARNING ") || !strings.Contains(got, "NotFound") {
+		t.Errorf("got:\n%s", got)
+	}
+}
+
+func TestCodeLevel(t *testing.T) {
+	for code, want := range map[codes.Code]string{
+		codes.OK:               "INFO",
+		codes.NotFound:         "INFO",
+		codes.Unavailable:      "WARNING",
+		codes.DeadlineExceeded: "WARNING",
+		codes.Internal:         "ERROR",
+		codes.Code(99):         "ERROR",
+	} {
+		if got := CodeSeverity(code); got != want {
+			t.Errorf("CodeSeverity(%v) = %s, want %s", code, got, want)
+		}
+	}
+}
+
+func TestDecodeTag(t *testing.T) {
+	for _, s := range []string{"noequals", "%zz=v", "k=%zz"} {
+		if _, _, ok := decodeTag(s); ok {
+			t.Errorf("decodeTag(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package grpcalog
+
+import (
+	"google.golang.org/grpc/codes"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/leveled"
+)
+
+// Options holds option values.
+type Options struct {
+	propagate   []string
+	codeLevel   func(codes.Code) alog.Level
+	leveled     leveled.Logger
+	newID       func() string
+	trustHeader bool
+}
+
+// Option sets an option for the interceptors.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithPropagatedTags sets the keys of the tags that are carried over gRPC
+// metadata: client interceptors send the context's tags with these keys, and
+// server interceptors add the ones they receive to the context. Tags with other
+// keys aren't sent, and are ignored if received.
+//
+// If this option is not specified, only the request ID is propagated.
+func WithPropagatedTags(keys ...string) Option {
+	return func(o *Options) { o.propagate = append(o.propagate, keys...) }
+}
+
+// WithCodeLevel sets the function that picks the level of the completion entry
+// for a call from its status code.
+//
+// If this option is not specified, CodeLevel is used.
+func WithCodeLevel(f func(codes.Code) alog.Level) Option {
+	return func(o *Options) { o.codeLevel = f }
+}
+
+// WithLeveled logs the completion entries with l, using the method for their
+// level, in place of the *alog.Logger passed to the interceptor, which may then
+// be nil. Filtered loggers then apply their minimum level to the entries.
+func WithLeveled(l leveled.Logger) Option {
+	return func(o *Options) { o.leveled = l }
+}
+
+// WithRequestIDFunc sets the function server interceptors use to generate
+// request IDs for calls that don't come with a trusted one.
+//
+// If this option is not specified, requestid.New is used.
+func WithRequestIDFunc(f func() string) Option {
+	return func(o *Options) { o.newID = f }
+}
+
+// WithTrustedRequestID makes server interceptors use the request ID sent by
+// the client in the RequestIDKey metadata, if requestid.Valid accepts it, in
+// place of generating one. Use it for servers that are only called by other
+// services passing on their request IDs.
+//
+// If this option is not specified, request IDs sent by clients are ignored.
+func WithTrustedRequestID() Option {
+	return func(o *Options) { o.trustHeader = true }
+}
@@ -0,0 +1,133 @@
+// Package httplog provides HTTP server middleware that sets up request-scoped
+// logging and writes an access log.
+//
+// Middleware puts the request, its trace and a request ID in the context of
+// each request, so every entry logged while handling it can be tied back to
+// it, and logs one entry for the request once it has been handled. With gkelog,
+// that entry has a fully populated httpRequest object.
+package httplog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+	"github.com/vimeo/alog/v3/requestid"
+)
+
+// now is replaced by tests.
+var now = time.Now
+
+// StatusLevel is the default level of the access log entry for a response
+// with status: LevelError for 5xx, LevelWarning for 4xx, and LevelInfo
+// otherwise.
+func StatusLevel(status int) alog.Level {
+	switch {
+	case status >= 500:
+		return alog.LevelError
+	case status >= 400:
+		return alog.LevelWarning
+	}
+	return alog.LevelInfo
+}
+
+// Middleware returns HTTP middleware that sets up the context of each request
+// for logging, and logs the request with logger once it has been handled.
+//
+// The context of the request gets:
+//   - the request, and its trace read from the headers set with
+//     WithTraceHeaders, with gkelog.WithRequest
+//   - a request ID, with requestid.WithID. It's generated, or taken from the
+//     request ID header with WithTrustedRequestID. It's also set on the
+//     response header.
+//
+// The access log entry has the status, size and latency of the response set
+// with gkelog.WithRequestStatus, gkelog.WithResponseSize and
+// gkelog.WithRequestLatency, and the number of bytes the handler read from the
+// request body with gkelog.WithRequestSize. Only that entry gets the client's
+// address and the protocol, with gkelog.WithRequestConnection. Its message has
+// the method, URL, status, size and latency, for emitters other than gkelog.
+// Its level is set by WithStatusLevel.
+//
+// The ResponseWriter passed on implements http.Flusher and http.Hijacker if
+// the original one does. Hijacked connections are logged without a status. If
+// the handler panics, the request is logged with a 500 status if none was
+// written, and the panic continues.
+func Middleware(logger *alog.Logger, opt ...Option) func(http.Handler) http.Handler {
+	o := &Options{
+		header: DefaultRequestIDHeader,
+		newID:  requestid.New,
+		level:  StatusLevel,
+	}
+	for _, option := range opt {
+		option(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := now()
+
+			var id string
+			if v := r.Header.Get(o.header); o.trustHeader && requestid.Valid(v) {
+				id = v
+			}
+			if id == "" {
+				id = o.newID()
+			}
+			w.Header().Set(o.header, id)
+
+			ctx := requestid.WithID(r.Context(), id)
+			ctx = gkelog.WithRequest(ctx, r, o.trace...)
+			r = r.WithContext(ctx)
+
+			var b *body
+			if r.Body != nil && r.Body != http.NoBody {
+				b = &body{ReadCloser: r.Body}
+				r.Body = b
+			}
+			rec, rw := wrap(w)
+
+			defer func() {
+				p := recover()
+				if p != nil && rec.status == 0 && !rec.hijacked {
+					rec.status = http.StatusInternalServerError
+				}
+				logRequest(ctx, logger, o, r, rec, b, now().Sub(start))
+				if p != nil {
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+func logRequest(ctx context.Context, logger *alog.Logger, o *Options, r *http.Request, rec *recorder, b *body, latency time.Duration) {
+	status := rec.status
+	if status == 0 && !rec.hijacked {
+		// The server writes a 200 for handlers that don't write anything.
+		status = http.StatusOK
+	}
+	reqSize := int64(0)
+	if b != nil {
+		reqSize = b.n
+	}
+
+	ctx = gkelog.WithRequestConnection(ctx)
+	if status != 0 {
+		ctx = gkelog.WithRequestStatus(ctx, status)
+	}
+	ctx = gkelog.WithResponseSize(ctx, rec.size)
+	ctx = gkelog.WithRequestSize(ctx, reqSize)
+	ctx = gkelog.WithRequestLatency(ctx, latency)
+	ctx = alog.WithLevel(ctx, o.level(status))
+
+	statusText := "hijacked"
+	if status != 0 {
+		statusText = fmt.Sprint(status)
+	}
+	logger.Printf(ctx, "%s %s %s %dB %s", r.Method, r.URL.RequestURI(), statusText, rec.size, latency)
+}
@@ -0,0 +1,292 @@
+package httplog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+	"github.com/vimeo/alog/v3/emitter/textlog"
+	"github.com/vimeo/alog/v3/requestid"
+)
+
+var zeroTimeOpt = alog.OverrideTimestamp(func() time.Time { return time.Time{} })
+
+// fakeNow makes each call to now 1.5s later than the previous one.
+func fakeNow(t *testing.T) {
+	var tm time.Time
+	now = func() time.Time {
+		tm = tm.Add(1500 * time.Millisecond)
+		return tm
+	}
+	t.Cleanup(func() { now = time.Now })
+}
+
+func TestMiddleware(t *testing.T) {
+	fakeNow(t)
+	b := &bytes.Buffer{}
+	logger := alog.New(alog.WithEmitter(gkelog.Emitter(gkelog.WithWriter(b))), zeroTimeOpt)
+
+	h := Middleware(logger, WithTrustedRequestID())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := requestid.FromContext(r.Context()); got != "abc" {
+			t.Errorf("requestid.FromContext = %q", got)
+		}
+		io.Copy(io.Discard, r.Body)
+		gkelog.LogInfo(r.Context(), logger, "handling")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/things?x=1", strings.NewReader("a body"))
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("X-Cloud-Trace-Context", "a2fbf27a2ed90077e0d4af0e40a241f9/12345")
+	req.Header.Set("Authorization", "Bearer abc")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if got := w.Header().Get("X-Request-Id"); got != "abc" {
+		t.Errorf("response request ID = %q", got)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d entries:\n%s", len(lines), b)
+	}
+
+	var handling, access struct {
+		Severity    string                 `json:"severity"`
+		Message     string                 `json:"message"`
+		RequestID   string                 `json:"request_id"`
+		Trace       string                 `json:"logging.googleapis.com/trace"`
+		HTTPRequest map[string]interface{} `json:"httpRequest"`
+		HTTPHeaders map[string][]string    `json:"httpHeaders"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &handling); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &access); err != nil {
+		t.Fatal(err)
+	}
+	if handling.RequestID != "abc" || handling.Trace != "a2fbf27a2ed90077e0d4af0e40a241f9" ||
+		handling.HTTPRequest["requestUrl"] != "/things?x=1" || handling.HTTPRequest["remoteIp"] != nil {
+		t.Errorf("handler entry: %s", lines[0])
+	}
+
+	if access.Severity != "INFO" || access.Message != "POST /things?x=1 201 5B 1.5s" || access.RequestID != "abc" {
+		t.Errorf("access entry: %s", lines[1])
+	}
+	want := map[string]interface{}{
+		"status":        201.0,
+		"responseSize":  5.0,
+		"latency":       "1.5s",
+		"requestMethod": "POST",
+		"requestUrl":    "/things?x=1",
+		"requestSize":   6.0,
+		"remoteIp":      "192.0.2.1",
+		"protocol":      "HTTP/1.1",
+	}
+	for k, v := range want {
+		if got := access.HTTPRequest[k]; got != v {
+			t.Errorf("httpRequest.%s = %v, want %v", k, got, v)
+		}
+	}
+	if got := access.HTTPHeaders["Authorization"]; got != nil {
+		t.Errorf("httpHeaders.Authorization = %q", got)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := alog.New(alog.WithEmitter(textlog.Emitter(b)), zeroTimeOpt)
+	var ids []string
+	newID := func() string {
+		ids = append(ids, "generated")
+		return "generated"
+	}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, tc := range []struct {
+		opts   []Option
+		sent   string
+		header string
+		want   string
+	}{
+		{nil, "sent", "X-Request-Id", "generated"},
+		{[]Option{WithTrustedRequestID()}, "sent", "X-Request-Id", "sent"},
+		{[]Option{WithTrustedRequestID()}, "not valid", "X-Request-Id", "generated"},
+		{[]Option{WithTrustedRequestID(), WithRequestIDHeader("x-trace-id")}, "sent", "X-Trace-Id", "generated"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-Id", tc.sent)
+		w := httptest.NewRecorder()
+		opts := append([]Option{WithRequestIDFunc(newID)}, tc.opts...)
+		Middleware(logger, opts...)(h).ServeHTTP(w, req)
+		if got := w.Header().Get(tc.header); got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+	if len(ids) != 3 {
+		t.Errorf("generated %d IDs, want 3", len(ids))
+	}
+}
+
+func TestTraceHeaders(t *testing.T) {
+	const (
+		cloudTrace  = "a2fbf27a2ed90077e0d4af0e40a241f9"
+		parentTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	)
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, cloudTrace},
+		{"traceparent first", []Option{WithTraceHeaders(gkelog.TraceParent, gkelog.CloudTraceContext)}, parentTrace},
+		{"traceparent only", []Option{WithTraceHeaders(gkelog.TraceParent)}, parentTrace},
+		{"cloud only", []Option{WithTraceHeaders(gkelog.CloudTraceContext)}, cloudTrace},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			logger := alog.New(alog.WithEmitter(gkelog.Emitter(gkelog.WithWriter(b))), zeroTimeOpt)
+			h := Middleware(logger, tc.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.Print(r.Context(), "handling")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Cloud-Trace-Context", cloudTrace+"/12345;o=1")
+			req.Header.Set("Traceparent", "00-"+parentTrace+"-00f067aa0ba902b7-01")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+
+			lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("got %d entries:\n%s", len(lines), b)
+			}
+			for _, line := range lines {
+				var entry struct {
+					Trace string `json:"logging.googleapis.com/trace"`
+				}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatal(err)
+				}
+				if entry.Trace != tc.want {
+					t.Errorf("trace = %q, want %q", entry.Trace, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusLevel(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := alog.New(alog.WithEmitter(textlog.Emitter(b)), zeroTimeOpt)
+	fakeNow(t)
+	for _, status := range []int{http.StatusNotFound, http.StatusBadGateway} {
+		h := Middleware(logger, WithRequestIDFunc(func() string { return "id" }))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "oops", status)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	want := "WARNING   [request_id=id] GET / 404 5B 1.5s\nERROR     [request_id=id] GET / 502 5B 1.5s\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := alog.New(alog.WithEmitter(textlog.Emitter(b)), zeroTimeOpt)
+	fakeNow(t)
+	h := Middleware(logger, WithRequestIDFunc(func() string { return "id" }))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v", p)
+			}
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	want := "ERROR     [request_id=id] GET / 500 0B 1.5s\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that can be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c, _ := net.Pipe()
+	return c, nil, nil
+}
+
+// plainWriter is a ResponseWriter with none of the optional interfaces.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestWrap(t *testing.T) {
+	for _, tc := range []struct {
+		w         http.ResponseWriter
+		canFlush  bool
+		canHijack bool
+	}{
+		{plainWriter{httptest.NewRecorder()}, false, false},
+		{httptest.NewRecorder(), true, false},
+		{struct {
+			plainWriter
+			http.Hijacker
+		}{plainWriter{httptest.NewRecorder()}, hijackRecorder{}}, false, true},
+		{hijackRecorder{httptest.NewRecorder()}, true, true},
+	} {
+		_, w := wrap(tc.w)
+		_, canFlush := w.(http.Flusher)
+		_, canHijack := w.(http.Hijacker)
+		if canFlush != tc.canFlush || canHijack != tc.canHijack {
+			t.Errorf("%T: Flusher %v, Hijacker %v, want %v, %v", tc.w, canFlush, canHijack, tc.canFlush, tc.canHijack)
+		}
+		// http.ResponseController finds the other interfaces this way.
+		if u, ok := w.(interface{ Unwrap() http.ResponseWriter }); !ok || u.Unwrap() != tc.w {
+			t.Errorf("%T: doesn't unwrap to the original", tc.w)
+		}
+	}
+}
+
+func TestFlushHijack(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := alog.New(alog.WithEmitter(textlog.Emitter(b)), zeroTimeOpt)
+	fakeNow(t)
+	mw := Middleware(logger, WithRequestIDFunc(func() string { return "id" }))
+
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+	})).ServeHTTP(hijackRecorder{rec}, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if !rec.Flushed {
+		t.Error("not flushed")
+	}
+
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	})).ServeHTTP(hijackRecorder{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+	want := "INFO      [request_id=id] GET /stream 200 0B 1.5s\nINFO      [request_id=id] GET /ws hijacked 0B 1.5s\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
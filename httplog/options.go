@@ -0,0 +1,68 @@
+package httplog
+
+import (
+	"net/http"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+)
+
+// DefaultRequestIDHeader is the header the request ID is read from and written
+// to if WithRequestIDHeader is not specified.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// Options holds option values.
+type Options struct {
+	header      string
+	trustHeader bool
+	newID       func() string
+	level       func(status int) alog.Level
+	trace       []gkelog.TraceHeader
+}
+
+// Option sets an option for Middleware.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithRequestIDHeader sets the header that the request ID is read from, and
+// written to on the response.
+//
+// If this option is not specified, DefaultRequestIDHeader is used.
+func WithRequestIDHeader(name string) Option {
+	return func(o *Options) { o.header = http.CanonicalHeaderKey(name) }
+}
+
+// WithTrustedRequestID makes the middleware use the request ID sent by the
+// client in the request ID header, if requestid.Valid accepts it, in place of
+// generating one. Use it for servers behind a proxy that sets the request ID,
+// and removes the one sent by clients.
+//
+// If this option is not specified, request IDs sent by clients are ignored.
+func WithTrustedRequestID() Option {
+	return func(o *Options) { o.trustHeader = true }
+}
+
+// WithRequestIDFunc sets the function that generates request IDs.
+//
+// If this option is not specified, requestid.New is used.
+func WithRequestIDFunc(f func() string) Option {
+	return func(o *Options) { o.newID = f }
+}
+
+// WithStatusLevel sets the function that picks the level of the access log
+// entry for a request from its response status.
+//
+// If this option is not specified, StatusLevel is used.
+func WithStatusLevel(f func(status int) alog.Level) Option {
+	return func(o *Options) { o.level = f }
+}
+
+// WithTraceHeaders sets the headers the trace of each request is read from, in
+// order of precedence. See gkelog.SpanContextFromRequest.
+//
+// If this option is not specified, gkelog's default headers are read:
+// X-Cloud-Trace-Context, then traceparent.
+func WithTraceHeaders(headers ...gkelog.TraceHeader) Option {
+	return func(o *Options) { o.trace = headers }
+}
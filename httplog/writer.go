@@ -0,0 +1,99 @@
+package httplog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// recorder is a ResponseWriter that records the status and size of the
+// response.
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	size     int64
+	hijacked bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	// Informational responses such as 103 Early Hints can be followed by
+	// the real one.
+	if r.status == 0 && status >= 200 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (r *recorder) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := r.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		r.hijacked = true
+	}
+	return conn, rw, err
+}
+
+type flusher struct{ *recorder }
+
+func (f flusher) Flush() { f.flush() }
+
+type hijacker struct{ *recorder }
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return h.hijack() }
+
+type flushHijacker struct{ *recorder }
+
+func (f flushHijacker) Flush() { f.flush() }
+
+func (f flushHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) { return f.hijack() }
+
+// wrap returns a recorder for w, and a ResponseWriter using it that implements
+// http.Flusher and http.Hijacker if w does, so handlers that check for them
+// keep working.
+func wrap(w http.ResponseWriter) (*recorder, http.ResponseWriter) {
+	r := &recorder{ResponseWriter: w}
+	_, canFlush := w.(http.Flusher)
+	_, canHijack := w.(http.Hijacker)
+	switch {
+	case canFlush && canHijack:
+		return r, flushHijacker{r}
+	case canFlush:
+		return r, flusher{r}
+	case canHijack:
+		return r, hijacker{r}
+	}
+	return r, r
+}
+
+// body is a request body that counts the bytes read from it.
+type body struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
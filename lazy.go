@@ -0,0 +1,103 @@
+package alog
+
+import "context"
+
+type lazyKey struct{}
+
+var lazyCtxKey = lazyKey{}
+
+// maxResolveDepth limits how many times a Valuer returning another Valuer is
+// resolved.
+const maxResolveDepth = 8
+
+// LazyTag is a string tag whose value is only computed if an entry is emitted.
+type LazyTag struct {
+	Key string
+	Val func() string
+}
+
+// Valuer is implemented by structured tag values that are only computed if an
+// entry is emitted. Emitters use the result of LogValue in its place.
+type Valuer interface {
+	LogValue() interface{}
+}
+
+// ValuerFunc is an adapter to allow the use of an ordinary function as a
+// Valuer.
+type ValuerFunc func() interface{}
+
+// LogValue calls f().
+func (f ValuerFunc) LogValue() interface{} {
+	return f()
+}
+
+// AddLazyTags adds tags whose values are computed when an entry is emitted to
+// the Context.
+//
+// The resolved tags are placed after the tags added with AddTags, so they take
+// precedence over tags with the same key. Adding a lazy tag with a key that is
+// already present replaces the old one.
+func AddLazyTags(ctx context.Context, tags ...LazyTag) context.Context {
+	tags = uniqueTags(tags, lazyTagKey)
+	return context.WithValue(ctx, lazyCtxKey, lazyTagNodeFromContext(ctx).with(opAdd, tags, nil))
+}
+
+// lazyTagsFromContext returns the lazy tags in the Context.
+func lazyTagsFromContext(ctx context.Context) []LazyTag {
+	return lazyTagNodeFromContext(ctx).list(lazyTagKey)
+}
+
+// lazyTagNodeFromContext wraps the type assertion coming out of a Context.
+func lazyTagNodeFromContext(ctx context.Context) *tagNode[LazyTag] {
+	n, _ := ctx.Value(lazyCtxKey).(*tagNode[LazyTag])
+	return n
+}
+
+// Resolve computes the values of the lazy tags of e, appending them to Tags,
+// and replaces the Valuers in STags with their values. The Logger calls it
+// once it has decided to emit an entry, before passing the entry on, so lazy
+// values are only computed for entries that pass the Logger's level, and
+// every emitter sees the resolved tags.
+//
+// Resolve only does work the first time it's called on an Entry. The tag
+// slices are copied rather than modified in place, since they are shared with
+// the Context.
+func (e *Entry) Resolve() {
+	if len(e.LazyTags) > 0 {
+		lazy := make([][2]string, len(e.LazyTags))
+		for i, t := range e.LazyTags {
+			lazy[i] = [2]string{t.Key, t.Val()}
+		}
+		e.Tags = mergeTags(e.Tags, lazy, tagKey)
+		e.LazyTags = nil
+	}
+
+	var sTags []STag
+	for i, t := range e.STags {
+		v, ok := t.Val.(Valuer)
+		if !ok {
+			continue
+		}
+		if sTags == nil {
+			sTags = make([]STag, len(e.STags))
+			copy(sTags, e.STags)
+		}
+		sTags[i].Val = resolve(v)
+	}
+	if sTags != nil {
+		e.STags = sTags
+	}
+}
+
+// resolve calls LogValue until it gets something other than a Valuer.
+func resolve(v Valuer) interface{} {
+	var val interface{} = v
+	for i := 0; i < maxResolveDepth; i++ {
+		v, ok := val.(Valuer)
+		if !ok {
+			break
+		}
+		val = v.LogValue()
+	}
+	return val
+}
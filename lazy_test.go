@@ -0,0 +1,97 @@
+package alog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestLazyTags(t *testing.T) {
+	t.Parallel()
+	buf := &bytes.Buffer{}
+	l := New(WithEmitter(EmitterFunc(func(ctx context.Context, e *Entry) {
+		// The Logger resolves the entry before emitting it, and resolving it
+		// again does nothing.
+		if len(e.LazyTags) > 0 {
+			t.Errorf("unresolved lazy tags: %v", e.LazyTags)
+		}
+		e.Resolve()
+		fmt.Fprintf(buf, "%v %v %s\n", e.Tags, e.STags, e.Msg)
+	})))
+
+	calls := 0
+	ctx := AddLazyTags(context.Background(), LazyTag{Key: "user", Val: func() string {
+		calls++
+		return "alice"
+	}})
+	ctx = AddTags(ctx, "a", "b")
+	ctx = AddStructuredTags(ctx, STag{Key: "n", Val: ValuerFunc(func() interface{} {
+		calls++
+		return ValuerFunc(func() interface{} { return 42 })
+	})})
+	l.Print(ctx, "test")
+
+	if got, want := buf.String(), "[[a b] [user alice]] [{n 42}] test\n"; got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+	if calls != 2 {
+		t.Errorf("lazy values computed %d times, want 2", calls)
+	}
+	if tags := tagsFromContext(ctx); len(tags) != 1 {
+		t.Errorf("context tags modified: %v", tags)
+	}
+	if _, ok := sTagsFromContext(ctx)[0].Val.(Valuer); !ok {
+		t.Error("context structured tags modified")
+	}
+}
+
+func TestLazyTagsFiltered(t *testing.T) {
+	t.Parallel()
+	var got []string
+	l := New(
+		WithEmitter(EmitterFunc(func(ctx context.Context, e *Entry) {
+			got = append(got, fmt.Sprintf("%v %s %t", e.Tags, e.Msg, len(e.Stack) > 0))
+		})),
+		WithFilter(LevelFilter(LevelWarning)),
+		WithStack(LevelNone),
+	)
+
+	calls := 0
+	ctx := AddLazyTags(context.Background(), LazyTag{Key: "user", Val: func() string {
+		calls++
+		return "alice"
+	}})
+	l.Print(WithLevel(ctx, LevelDebug), "dropped")
+	if calls != 0 {
+		t.Errorf("lazy tag of a dropped entry computed %d times", calls)
+	}
+	l.Print(WithLevel(ctx, LevelError), "kept")
+
+	if got, want := fmt.Sprint(got), "[[[user alice]] kept true]"; got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+	if calls != 1 {
+		t.Errorf("lazy tag computed %d times, want 1", calls)
+	}
+}
+
+func TestLazyTagsMultiEmitter(t *testing.T) {
+	t.Parallel()
+	a, b := &bytes.Buffer{}, &bytes.Buffer{}
+	l := New(WithEmitter(MultiEmitter(
+		Branch{Emitter: bufEmitter(a)},
+		Branch{Emitter: bufEmitter(b), Filter: TagFilter("user", "alice")},
+	)))
+
+	calls := 0
+	ctx := AddLazyTags(context.Background(), LazyTag{Key: "user", Val: func() string {
+		calls++
+		return "alice"
+	}})
+	l.Print(ctx, "test")
+
+	if a.String() != b.String() || calls != 1 {
+		t.Errorf("got %#q and %#q after %d calls", a.String(), b.String(), calls)
+	}
+}
@@ -0,0 +1,77 @@
+package alog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type levelKey struct{}
+type minLevelKey struct{}
+
+var levelCtxKey = levelKey{}
+var minLevelCtxKey = minLevelKey{}
+
+// Level is the severity of an Entry.
+//
+// The values follow the severities used by Google Cloud Logging, so that every
+// level can be represented natively by the bundled emitters. The zero Level
+// means no level was set.
+type Level uint8
+
+// Levels, in increasing order of severity.
+const (
+	LevelNone      Level = iota // none
+	LevelDebug                  // debug
+	LevelInfo                   // info
+	LevelNotice                 // notice
+	LevelWarning                // warning
+	LevelError                  // error
+	LevelCritical               // critical
+	LevelAlert                  // alert
+	LevelEmergency              // emergency
+)
+
+// WithLevel returns a copy of parent with the level of the entries logged with
+// it set to level.
+func WithLevel(parent context.Context, level Level) context.Context {
+	return context.WithValue(parent, levelCtxKey, level)
+}
+
+// WithMinLevel returns a copy of parent in which loggers that filter by level,
+// such as leveled.Filtered and gkelog's Log functions, log the entries of at
+// least level, in place of their own minimum level and any VModule overrides.
+// It's meant for scoping verbosity to a request: see the verbosity package.
+//
+// LevelNone logs every entry.
+func WithMinLevel(parent context.Context, level Level) context.Context {
+	return context.WithValue(parent, minLevelCtxKey, level)
+}
+
+// MinLevelFromContext returns the level set with WithMinLevel, and whether
+// there is one.
+func MinLevelFromContext(ctx context.Context) (Level, bool) {
+	l, ok := ctx.Value(minLevelCtxKey).(Level)
+	return l, ok
+}
+
+// ParseLevel returns the Level named s, as returned by Level.String. Case is
+// ignored.
+func ParseLevel(s string) (Level, error) {
+	for l := LevelNone; l <= LevelEmergency; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return LevelNone, fmt.Errorf("unknown level %q", s)
+}
+
+// levelFromContext wraps the type assertion coming out of a Context.
+func levelFromContext(ctx context.Context) Level {
+	if l, ok := ctx.Value(levelCtxKey).(Level); ok {
+		return l
+	}
+	return LevelNone
+}
+
+//go:generate go run golang.org/x/tools/cmd/stringer@latest -type Level -linecomment
@@ -0,0 +1,99 @@
+// Command loglevel reports or changes the log level of a running process that
+// serves a levelctl.Handler.
+//
+// Usage:
+//
+//	loglevel -socket /run/app/loglevel.sock [-ttl 10m] [level]
+//	loglevel -url http://localhost:8080/debug/loglevel [-ttl 10m] [level]
+//
+// Without a level, the current level is printed. With one, the level is set
+// for the TTL, or for the server's default TTL if -ttl is not given; -ttl 0
+// keeps it until it's changed again.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vimeo/alog/v3/leveled/levelctl"
+)
+
+func main() {
+	socket := flag.String("socket", "", "path of the Unix socket served by levelctl.ListenUnix")
+	endpoint := flag.String("url", "", "URL of the levelctl.Handler")
+	ttl := flag.String("ttl", "", "how long the new level lasts")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s (-socket path | -url url) [-ttl duration] [level]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if (*socket == "") == (*endpoint == "") || flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	u := *endpoint
+	if *socket != "" {
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", *socket)
+			},
+		}
+		u = "http://unix/debug/loglevel"
+	}
+
+	var req *http.Request
+	var err error
+	if flag.NArg() == 0 {
+		req, err = http.NewRequest(http.MethodGet, u, nil)
+	} else {
+		form := url.Values{"level": {flag.Arg(0)}}
+		if *ttl != "" {
+			form.Set("ttl", *ttl)
+		}
+		req, err = http.NewRequest(http.MethodPut, u, strings.NewReader(form.Encode()))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fail(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		fail(fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body))))
+	}
+
+	var s levelctl.State
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		fail(err)
+	}
+	if s.Expires != nil {
+		fmt.Printf("%s (until %s)\n", s.Level, s.Expires.Local().Format(time.RFC3339))
+	} else {
+		fmt.Println(s.Level)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "loglevel:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,139 @@
+// Package levelctl lets the level of a leveled.LevelVar be changed on a running
+// process, over HTTP or a Unix socket.
+//
+// Levels raised this way revert automatically after a TTL, so verbose logging
+// turned on to debug an incident doesn't outlive it. The loglevel command in
+// cmd/loglevel is a client for both endpoints.
+package levelctl
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vimeo/alog/v3/leveled"
+)
+
+// State is the JSON body of the responses from the Handler.
+type State struct {
+	Level leveled.Level `json:"level"`
+
+	// Expires is when Level reverts, if it was set with a TTL.
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Handler returns an http.Handler that reports and changes the level of v. It's
+// meant to be mounted at /debug/loglevel, but answers on any path.
+//
+// A GET request returns the State of v. A PUT request sets the level from its
+// "level" query or form parameter, for the TTL in its "ttl" parameter, which
+// is parsed with time.ParseDuration; a TTL of 0 sets the level until it's
+// changed again. The new State is returned.
+func Handler(v *leveled.LevelVar, opt ...Option) http.Handler {
+	o := new(Options)
+	for _, option := range opt {
+		option(o)
+	}
+	if !o.ttlSet {
+		o.ttl = DefaultTTL
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut:
+			level, err := leveled.ParseLevel(r.FormValue("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			ttl := o.ttl
+			if s := r.FormValue("ttl"); s != "" {
+				if ttl, err = time.ParseDuration(s); err != nil || ttl < 0 {
+					http.Error(w, "invalid ttl "+s, http.StatusBadRequest)
+					return
+				}
+			}
+			if ttl > 0 {
+				v.SetFor(level, ttl)
+			} else {
+				v.Set(level)
+			}
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		s := State{Level: v.Level()}
+		if exp := v.Expires(); !exp.IsZero() {
+			s.Expires = &exp
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	})
+}
+
+// ListenUnix serves Handler(v, opt...) on a Unix socket created at path, until
+// the returned server is closed. The socket is only accessible to the user
+// that owns the process, and is removed when the server is closed. It's an
+// error for path to exist already.
+//
+// The socket is first created in a temporary directory next to path, so the
+// name in that directory must also fit in the limit on socket paths.
+func ListenUnix(path string, v *leveled.LevelVar, opt ...Option) (*http.Server, error) {
+	ln, err := listenUnix(path)
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{
+		Handler:           Handler(v, opt...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go srv.Serve(ln)
+	return srv, nil
+}
+
+// listenUnix listens on a Unix socket at path that only the current user can
+// access. The socket is created in a private directory, and only linked to path
+// once its mode is set, since it's open to anyone the umask allows until then.
+func listenUnix(path string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".lc")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	ln.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if err := os.Link(tmp, path); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return &unixListener{UnixListener: ln, path: path}, nil
+}
+
+// unixListener removes the socket at path when it's closed.
+type unixListener struct {
+	*net.UnixListener
+	path string
+	once sync.Once
+}
+
+func (l *unixListener) Close() error {
+	err := l.UnixListener.Close()
+	l.once.Do(func() { os.Remove(l.path) })
+	return err
+}
@@ -0,0 +1,131 @@
+package levelctl
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3/leveled"
+)
+
+func do(t *testing.T, h http.Handler, method, target string) (int, State) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	var s State
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, s
+}
+
+func TestHandler(t *testing.T) {
+	v := new(leveled.LevelVar)
+	v.Set(leveled.Warning)
+	h := Handler(v)
+
+	code, s := do(t, h, http.MethodGet, "/debug/loglevel")
+	if code != http.StatusOK || s.Level != leveled.Warning || s.Expires != nil {
+		t.Errorf("GET: %d %+v", code, s)
+	}
+
+	before := time.Now()
+	code, s = do(t, h, http.MethodPut, "/debug/loglevel?level=debug")
+	if code != http.StatusOK || s.Level != leveled.Debug || s.Expires == nil || s.Expires.Before(before.Add(DefaultTTL)) {
+		t.Errorf("PUT with the default TTL: %d %+v", code, s)
+	}
+	if v.Level() != leveled.Debug {
+		t.Errorf("level not set: %v", v.Level())
+	}
+
+	code, s = do(t, h, http.MethodPut, "/debug/loglevel?level=INFO&ttl=1h")
+	if code != http.StatusOK || s.Level != leveled.Info || s.Expires == nil || s.Expires.After(time.Now().Add(time.Hour)) {
+		t.Errorf("PUT with a TTL: %d %+v", code, s)
+	}
+
+	code, s = do(t, h, http.MethodPut, "/debug/loglevel?level=error&ttl=0")
+	if code != http.StatusOK || s.Level != leveled.Error || s.Expires != nil {
+		t.Errorf("PUT without a TTL: %d %+v", code, s)
+	}
+
+	for _, target := range []string{"/?level=verbose", "/?level=info&ttl=soon", "/?level=info&ttl=-1m"} {
+		if code, _ := do(t, h, http.MethodPut, target); code != http.StatusBadRequest {
+			t.Errorf("PUT %s: got %d, want %d", target, code, http.StatusBadRequest)
+		}
+	}
+	if code, _ := do(t, h, http.MethodPost, "/?level=info"); code != http.StatusMethodNotAllowed {
+		t.Errorf("POST: got %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+	if v.Level() != leveled.Error {
+		t.Errorf("level changed by a bad request: %v", v.Level())
+	}
+}
+
+func TestWithDefaultTTL(t *testing.T) {
+	v := new(leveled.LevelVar)
+	code, s := do(t, Handler(v, WithDefaultTTL(0)), http.MethodPut, "/?level=info")
+	if code != http.StatusOK || s.Level != leveled.Info || s.Expires != nil {
+		t.Errorf("got %d %+v", code, s)
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which t.TempDir can
+	// exceed.
+	dir, err := os.MkdirTemp("", "levelctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sock")
+
+	v := new(leveled.LevelVar)
+	srv, err := ListenUnix(path, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode: %v, %v", fi.Mode(), err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	req, _ := http.NewRequest(http.MethodPut, "http://unix/debug/loglevel", strings.NewReader("level=critical"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || v.Level() != leveled.Critical {
+		t.Errorf("got %s, level %v", resp.Status, v.Level())
+	}
+
+	if _, err := ListenUnix(path, v); err == nil {
+		t.Error("listening on an existing path didn't fail")
+	}
+	// Nothing is left behind in dir but the socket.
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Errorf("dir: %v, %v", entries, err)
+	}
+
+	srv.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket not removed: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package levelctl
+
+import "time"
+
+// DefaultTTL is how long a level set through the Handler lasts if the request
+// doesn't give a TTL and WithDefaultTTL is not specified.
+const DefaultTTL = 15 * time.Minute
+
+// Options holds option values.
+type Options struct {
+	ttl    time.Duration
+	ttlSet bool
+}
+
+// Option sets an option for the Handler.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithDefaultTTL sets how long a level set through the Handler lasts if the
+// request doesn't give a TTL. Zero means the level is kept until it's changed
+// again.
+//
+// If this option is not specified, DefaultTTL is used.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.ttl = ttl
+		o.ttlSet = true
+	}
+}
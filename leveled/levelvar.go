@@ -0,0 +1,133 @@
+package leveled
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// afterFunc is time.AfterFunc, replaced in tests.
+var afterFunc = func(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
+
+// ParseLevel returns the Level named s, as returned by Level.String. Case is
+// ignored.
+func ParseLevel(s string) (Level, error) {
+	for l := Debug; l <= Critical; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown level %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler using ParseLevel.
+func (l *Level) UnmarshalText(text []byte) error {
+	v, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// LevelVar is a Level that can be changed while it's in use, and shared by
+// many loggers. See FilteredVar.
+//
+// The zero value is Debug. A LevelVar must not be copied after first use.
+type LevelVar struct {
+	level atomic.Uint32
+
+	mu      sync.Mutex
+	base    Level       // the level to revert to when a SetFor expires
+	stop    func() bool // stops the pending revert, if there is one
+	gen     uint64      // incremented whenever the pending revert changes
+	expires time.Time
+}
+
+// Level returns the current level.
+func (v *LevelVar) Level() Level {
+	return Level(v.level.Load())
+}
+
+// Set sets the level, cancelling any revert scheduled by SetFor.
+func (v *LevelVar) Set(l Level) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.cancel()
+	v.base = l
+	v.level.Store(uint32(l))
+}
+
+// SetFor sets the level for ttl, after which it reverts to the level it had
+// before. Calling SetFor again before then replaces the level and the TTL, but
+// not the level that is reverted to.
+func (v *LevelVar) SetFor(l Level, ttl time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.stop == nil {
+		v.base = v.Level()
+	}
+	v.cancel()
+	v.level.Store(uint32(l))
+
+	gen := v.gen
+	v.stop = afterFunc(ttl, func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if v.gen != gen {
+			// Replaced by a later call after the timer fired.
+			return
+		}
+		v.cancel()
+		v.level.Store(uint32(v.base))
+	})
+	v.expires = time.Now().Add(ttl)
+}
+
+// Expires returns when the level set by SetFor reverts, or the zero Time if
+// no revert is pending.
+func (v *LevelVar) Expires() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.expires
+}
+
+// cancel stops the pending revert, if there is one. v.mu must be held.
+func (v *LevelVar) cancel() {
+	if v.stop != nil {
+		v.stop()
+		v.stop = nil
+	}
+	v.gen++
+	v.expires = time.Time{}
+}
+
+// String returns the name of the current level.
+func (v *LevelVar) String() string {
+	return v.Level().String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v *LevelVar) MarshalText() ([]byte, error) {
+	return v.Level().MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, calling Set with the
+// parsed level.
+func (v *LevelVar) UnmarshalText(text []byte) error {
+	l, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	v.Set(l)
+	return nil
+}
@@ -0,0 +1,116 @@
+package leveled
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/textlog"
+)
+
+func TestParseLevel(t *testing.T) {
+	for l := Debug; l <= Critical; l++ {
+		got, err := ParseLevel(l.String())
+		if err != nil || got != l {
+			t.Errorf("ParseLevel(%q) = %v, %v", l.String(), got, err)
+		}
+	}
+	if got, err := ParseLevel("WARNING"); err != nil || got != Warning {
+		t.Errorf("ParseLevel(WARNING) = %v, %v", got, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose) succeeded")
+	}
+}
+
+func TestSetMinLevelRace(t *testing.T) {
+	l := Filtered(alog.New(alog.WithEmitter(textlog.Emitter(io.Discard))))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.SetMinLevel(Level(i % 5))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.Info(ctx, "test")
+		}
+	}()
+	wg.Wait()
+}
+
+// fakeTimers replaces afterFunc for the duration of the test, and returns the
+// functions it was called with.
+func fakeTimers(t *testing.T) *[]func() {
+	var fs []func()
+	orig := afterFunc
+	afterFunc = func(d time.Duration, f func()) func() bool {
+		fs = append(fs, f)
+		return func() bool { return true }
+	}
+	t.Cleanup(func() { afterFunc = orig })
+	return &fs
+}
+
+func TestSetFor(t *testing.T) {
+	timers := fakeTimers(t)
+
+	v := new(LevelVar)
+	v.Set(Warning)
+	v.SetFor(Debug, time.Minute)
+	if got := v.Level(); got != Debug {
+		t.Fatalf("got %v, want debug", got)
+	}
+	if v.Expires().IsZero() {
+		t.Error("no expiry reported")
+	}
+
+	// A second call extends the first, and still reverts to Warning.
+	v.SetFor(Info, time.Minute)
+	(*timers)[0]() // the first timer, stopped but fired anyway
+	if got := v.Level(); got != Info {
+		t.Fatalf("stale revert: got %v, want info", got)
+	}
+	(*timers)[1]()
+	if got := v.Level(); got != Warning {
+		t.Fatalf("got %v, want warning", got)
+	}
+	if !v.Expires().IsZero() {
+		t.Error("expiry reported after revert")
+	}
+
+	// Set cancels the revert.
+	v.SetFor(Debug, time.Minute)
+	v.Set(Error)
+	(*timers)[2]()
+	if got := v.Level(); got != Error {
+		t.Fatalf("got %v, want error", got)
+	}
+}
+
+func TestFilteredVar(t *testing.T) {
+	v := new(LevelVar)
+	v.Set(Error)
+	var n int
+	em := alog.EmitterFunc(func(ctx context.Context, e *alog.Entry) { n++ })
+	a := FilteredVar(alog.New(alog.WithEmitter(em)), v)
+	b := FilteredVar(alog.New(alog.WithEmitter(em)), v)
+
+	ctx := context.Background()
+	a.Info(ctx, "dropped")
+	b.Info(ctx, "dropped")
+	v.Set(Info)
+	a.Info(ctx, "logged")
+	b.Info(ctx, "logged")
+	if n != 2 {
+		t.Errorf("got %d entries, want 2", n)
+	}
+}
@@ -3,6 +3,7 @@ package leveled
 import (
 	"context"
 	"fmt"
+	"runtime"
 
 	"github.com/vimeo/alog/v3"
 )
@@ -18,9 +19,44 @@ const (
 	Critical              // critical
 )
 
-// LevelKey is the tag key associated with a level.
+// LevelKey is the field name associated with a level, as used by the jsonlog
+// emitter.
 const LevelKey = "level"
 
+// AlogLevel returns the alog.Level corresponding to l.
+func (l Level) AlogLevel() alog.Level {
+	switch l {
+	case Debug:
+		return alog.LevelDebug
+	case Info:
+		return alog.LevelInfo
+	case Warning:
+		return alog.LevelWarning
+	case Error:
+		return alog.LevelError
+	case Critical:
+		return alog.LevelCritical
+	}
+	return alog.LevelNone
+}
+
+// FromAlogLevel returns the Level closest to an alog.Level. Notice maps to
+// Info, and the levels above Critical map to Critical. alog.LevelNone maps to
+// Debug.
+func FromAlogLevel(l alog.Level) Level {
+	switch {
+	case l <= alog.LevelDebug:
+		return Debug
+	case l <= alog.LevelNotice:
+		return Info
+	case l == alog.LevelWarning:
+		return Warning
+	case l == alog.LevelError:
+		return Error
+	}
+	return Critical
+}
+
 // Logger is an interface that implements logging functions for different levels of severity.
 type Logger interface {
 	// Debug logs debugging or trace information.
@@ -50,28 +86,52 @@ type FilteredLogger interface {
 type defaultLogger struct {
 	*alog.Logger
 
-	// Indicates the minimum level to log at.  If MinLevel is greater than the
+	// Indicates the minimum level to log at.  If it's greater than the
 	// level of a given log message, the log message will be suppressed.
-	MinLevel Level
+	level *LevelVar
+
+	// vmodule overrides level for some callers.
+	vmodule *alog.VModule
 }
 
 // Default returns a Logger that wraps the provided `alog.Logger`.
-// It adds a generic "level" tag that is not intended for special use by
-// emitters. If emitter-specific functionality around log levels is needed,
-// a different implementation of Logger should be used.
+// It sets the level of each entry (see alog.WithLevel), which the bundled
+// emitters render natively.
 func Default(logger *alog.Logger) Logger {
 	return &defaultLogger{
 		Logger: logger,
+		level:  new(LevelVar),
 	}
 }
 
 // Filtered returns a logger that allows for setting the minimum level.
-func Filtered(logger *alog.Logger) FilteredLogger {
+//
+// A minimum level set on the context with alog.WithMinLevel takes precedence
+// over the logger's, so verbosity can be raised for a single request.
+func Filtered(logger *alog.Logger, opt ...Option) FilteredLogger {
+	o := new(Options)
+	for _, option := range opt {
+		option(o)
+	}
+	if o.level == nil {
+		o.level = new(LevelVar)
+	}
 	return &defaultLogger{
-		Logger: logger,
+		Logger:  logger,
+		level:   o.level,
+		vmodule: o.vmodule,
 	}
 }
 
+// FilteredVar returns a logger whose minimum level is level, so that it can be
+// changed for many loggers at once. SetMinLevel on the logger sets level.
+//
+// It's safe to change level while the logger is in use. FilteredVar is
+// equivalent to Filtered(logger, WithLevelVar(level)).
+func FilteredVar(logger *alog.Logger, level *LevelVar) FilteredLogger {
+	return Filtered(logger, WithLevelVar(level))
+}
+
 // Debug implements Logger.Debug
 func (d *defaultLogger) Debug(ctx context.Context, f string, v ...interface{}) {
 	d.Log(ctx, Debug, f, v...)
@@ -99,15 +159,33 @@ func (d *defaultLogger) Critical(ctx context.Context, f string, v ...interface{}
 
 // Log implements FilteredLogger.Log
 func (d *defaultLogger) Log(ctx context.Context, level Level, f string, v ...interface{}) {
-	if level >= d.MinLevel {
-		ctx = alog.AddTags(ctx, LevelKey, level.String())
+	if level >= d.minLevel(ctx) {
+		ctx = alog.WithLevel(ctx, level.AlogLevel())
 		d.Logger.Output(ctx, 3, fmt.Sprintf(f, v...))
 	}
 }
 
+// minLevel returns the minimum level for the caller of the caller of Log: the
+// one set on ctx with alog.WithMinLevel if there is one, then the VModule
+// override, then d.level.
+func (d *defaultLogger) minLevel(ctx context.Context) Level {
+	if l, ok := alog.MinLevelFromContext(ctx); ok {
+		return FromAlogLevel(l)
+	}
+	if d.vmodule != nil {
+		var pcs [1]uintptr
+		runtime.Callers(4, pcs[:])
+		if l, ok := d.vmodule.MinLevel(pcs[0]); ok {
+			return FromAlogLevel(l)
+		}
+	}
+	return d.level.Level()
+}
+
 // SetMinLevel sets the minimum level that will be logged and implements FilteredLogger.
+// It's safe to call while the logger is in use.
 func (d *defaultLogger) SetMinLevel(level Level) {
-	d.MinLevel = level
+	d.level.Set(level)
 }
 
 //go:generate go run golang.org/x/tools/cmd/stringer@latest -type Level -linecomment
@@ -16,7 +16,7 @@ func TestLogger(t *testing.T) {
 	ctx := context.Background()
 	ctx = alog.AddTags(ctx, "key", "value")
 	l.Info(ctx, "")
-	const want = `[key=value level=info] ` + "\n"
+	const want = `INFO      [key=value] ` + "\n"
 	if got := b.String(); got != want {
 		t.Errorf("got: %#q, want: %#q", got, want)
 	}
@@ -31,7 +31,73 @@ func TestLogLevels(t *testing.T) {
 	ctx = alog.AddTags(ctx, "key", "value")
 	l.Error(ctx, "I get logged")
 	l.Debug(ctx, "I don't get logged")
-	const want = `[key=value level=error] I get logged` + "\n"
+	const want = `ERROR     [key=value] I get logged` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got: %#q, want: %#q", got, want)
+	}
+}
+
+func TestFromAlogLevel(t *testing.T) {
+	for l := Debug; l <= Critical; l++ {
+		if got := FromAlogLevel(l.AlogLevel()); got != l {
+			t.Errorf("FromAlogLevel(%v.AlogLevel()) = %v", l, got)
+		}
+	}
+	if got := FromAlogLevel(alog.LevelEmergency); got != Critical {
+		t.Errorf("FromAlogLevel(LevelEmergency) = %v, want %v", got, Critical)
+	}
+}
+
+func TestLazyTagsFiltered(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := Filtered(alog.New(alog.WithEmitter(textlog.Emitter(b))))
+	l.SetMinLevel(Info)
+
+	ctx := alog.AddLazyTags(context.Background(), alog.LazyTag{Key: "key", Val: func() string {
+		t.Error("lazy tag computed for a filtered entry")
+		return ""
+	}})
+	l.Debug(ctx, "I don't get logged")
+}
+
+func TestVModule(t *testing.T) {
+	b := &bytes.Buffer{}
+	m, err := alog.ParseVModule("leveled/logger_test=debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Filtered(alog.New(alog.WithEmitter(textlog.Emitter(b))), WithVModule(m))
+	l.SetMinLevel(Error)
+
+	ctx := context.Background()
+	l.Debug(ctx, "logged by override")
+	m.Set("logger_test=warning")
+	l.Info(ctx, "not logged")
+	l.Warning(ctx, "logged by override")
+	m.Set("")
+	l.Warning(ctx, "not logged")
+
+	const want = "DEBUG     logged by override\nWARNING   logged by override\n"
+	if got := b.String(); got != want {
+		t.Errorf("got: %#q, want: %#q", got, want)
+	}
+}
+
+func TestContextMinLevel(t *testing.T) {
+	b := &bytes.Buffer{}
+	m, err := alog.ParseVModule("logger_test=error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Filtered(alog.New(alog.WithEmitter(textlog.Emitter(b))), WithVModule(m))
+	l.SetMinLevel(Warning)
+
+	ctx := context.Background()
+	l.Info(alog.WithMinLevel(ctx, alog.LevelInfo), "logged by context")
+	l.Warning(ctx, "not logged")
+	l.Error(alog.WithMinLevel(ctx, alog.LevelCritical), "not logged")
+
+	const want = "INFO      logged by context\n"
 	if got := b.String(); got != want {
 		t.Errorf("got: %#q, want: %#q", got, want)
 	}
@@ -0,0 +1,29 @@
+package leveled
+
+import "github.com/vimeo/alog/v3"
+
+// Options holds option values.
+type Options struct {
+	level   *LevelVar
+	vmodule *alog.VModule
+}
+
+// Option sets an option for a logger returned by Filtered.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// WithLevelVar sets the variable holding the minimum level, so that it can be
+// shared with other loggers and changed while they're in use.
+//
+// If this option is not specified, the logger gets a LevelVar of its own.
+func WithLevelVar(v *LevelVar) Option {
+	return func(o *Options) { o.level = v }
+}
+
+// WithVModule sets overrides of the minimum level for the callers in
+// particular files or packages. Each override is mapped to a Level with
+// FromAlogLevel.
+func WithVModule(m *alog.VModule) Option {
+	return func(o *Options) { o.vmodule = m }
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"sync"
 	"time"
 )
 
@@ -28,6 +29,11 @@ func New(opts ...Option) *Logger {
 // Emitter is the interface that wraps the Emit method.
 //
 // Emit handles a log entry in a customized way.
+//
+// The Entry is only valid until Emit returns: the Logger reuses it for later
+// entries. Emitters that keep an entry after returning, such as those that
+// hand entries off to another goroutine, must copy the Entry struct. The tag
+// slices and the stack are never modified, so a shallow copy is enough.
 type Emitter interface {
 	Emit(context.Context, *Entry)
 }
@@ -48,9 +54,18 @@ func (f EmitterFunc) Emit(c context.Context, e *Entry) {
 // The default text format will have a newline appended if one is not present in
 // the message.
 type Logger struct {
-	caller  bool
-	emitter Emitter
-	now     func() time.Time
+	caller       bool
+	emitter      Emitter
+	errorHandler ErrorHandler
+	filter       Filter
+	now          func() time.Time
+	stack        bool
+	stackLevel   Level
+
+	// tags and sTags are added to every entry, ahead of the tags from the
+	// context. See With and WithStructured.
+	tags  [][2]string
+	sTags []STag
 }
 
 // Output emits the supplied string while capturing the caller information
@@ -63,26 +78,134 @@ func (l *Logger) Output(ctx context.Context, calldepth int, msg string) {
 	if l == nil || l.emitter == nil {
 		return
 	}
+	e := l.newEntry(ctx, msg)
+	defer putEntry(e)
+
+	if l.caller {
+		var ok bool
+		_, e.File, e.Line, ok = runtime.Caller(calldepth)
+		if !ok {
+			e.File = "???"
+			e.Line = 0
+		}
+	}
+	if l.filter != nil && !l.filter(ctx, e) {
+		return
+	}
+	if l.wantStack(ctx, e) {
+		e.Stack = stackFrames(callers(calldepth + 1))
+		e.Goroutine = goroutineID()
+	}
+
+	l.emit(ctx, e)
+}
+
+// OutputPC is like Output, but takes the caller information from pc, a program
+// counter as returned by runtime.Callers, rather than walking the call stack.
+// It's meant for adapters that have already captured the caller, such as a
+// slog.Record. A zero pc is reported the same way as an unknown caller.
+func (l *Logger) OutputPC(ctx context.Context, pc uintptr, msg string) {
+	if l == nil || l.emitter == nil {
+		return
+	}
+	e := l.newEntry(ctx, msg)
+	defer putEntry(e)
+
+	if l.caller {
+		e.File = "???"
+		if pc != 0 {
+			f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+			if f.File != "" {
+				e.File, e.Line = f.File, f.Line
+			}
+		}
+	}
+	if l.filter != nil && !l.filter(ctx, e) {
+		return
+	}
+	if l.wantStack(ctx, e) {
+		e.Stack = stackFrames(framesFrom(callers(2), pc))
+		e.Goroutine = goroutineID()
+	}
+
+	l.emit(ctx, e)
+}
+
+// emit resolves the lazy tags of e, and passes it to the emitter along with the
+// error handler if there is one.
+func (l *Logger) emit(ctx context.Context, e *Entry) {
+	e.Resolve()
+	if l.errorHandler != nil {
+		ctx = context.WithValue(ctx, errorHandlerCtxKey, l.errorHandler)
+	}
+	l.emitter.Emit(ctx, e)
+}
+
+var entryPool = sync.Pool{
+	New: func() interface{} { return new(Entry) },
+}
+
+// putEntry clears e and puts it back into the pool.
+func putEntry(e *Entry) {
+	*e = Entry{}
+	entryPool.Put(e)
+}
+
+// newEntry builds the Entry for msg from the Logger's clock and tags, and the
+// level and tags in ctx. The Entry comes from a pool, and should be returned
+// with putEntry once it has been emitted.
+func (l *Logger) newEntry(ctx context.Context, msg string) *Entry {
 	if l.now == nil {
 		l.now = time.Now
 	}
-	e := Entry{
+	e := entryPool.Get().(*Entry)
+	*e = Entry{
 		Time:  l.now(),
+		Level: levelFromContext(ctx),
 		Tags:  tagsFromContext(ctx),
 		STags: sTagsFromContext(ctx),
 		Msg:   msg,
+
+		LazyTags: lazyTagsFromContext(ctx),
+	}
+	if len(l.tags) > 0 {
+		e.Tags = mergeTags(l.tags, e.Tags, tagKey)
 	}
+	if len(l.sTags) > 0 {
+		e.STags = mergeTags(l.sTags, e.STags, sTagKey)
+	}
+	return e
+}
 
-	if l.caller {
-		var ok bool
-		_, e.File, e.Line, ok = runtime.Caller(calldepth)
-		if !ok {
-			e.File = "???"
-			e.Line = 0
-		}
+// With returns a child Logger that adds paired strings to the tags of every
+// entry it emits. Any unpaired strings are ignored.
+//
+// The Logger's tags come before the tags in the Context, so that a tag added
+// with AddTags replaces a Logger tag with the same key. Tags added to the
+// child replace the tags of l with the same key, and come after the others.
+//
+// With on a nil *Logger returns nil.
+func (l *Logger) With(pairs ...string) *Logger {
+	if l == nil {
+		return nil
 	}
+	c := *l
+	c.tags = mergeTags(l.tags, pairsToTags(pairs), tagKey)
+	return &c
+}
 
-	l.emitter.Emit(ctx, &e)
+// WithStructured returns a child Logger that adds tags to the structured tags
+// of every entry it emits. They are ordered the same way as the tags added by
+// With.
+//
+// WithStructured on a nil *Logger returns nil.
+func (l *Logger) WithStructured(tags ...STag) *Logger {
+	if l == nil {
+		return nil
+	}
+	c := *l
+	c.sTags = mergeTags(l.sTags, uniqueTags(tags, sTagKey), sTagKey)
+	return &c
 }
 
 // Print calls l.Output to emit a log entry. Arguments are handled like
@@ -0,0 +1,252 @@
+package alog
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Filter reports whether an entry should be passed on to an emitter.
+type Filter func(ctx context.Context, e *Entry) bool
+
+// TagFilter returns a Filter that accepts entries which have a tag with the
+// given key. If values are supplied, the tag must also have one of them.
+func TagFilter(key string, values ...string) Filter {
+	return func(ctx context.Context, e *Entry) bool {
+		for _, t := range e.Tags {
+			if t[0] != key {
+				continue
+			}
+			if len(values) == 0 {
+				return true
+			}
+			for _, v := range values {
+				if t[1] == v {
+					return true
+				}
+			}
+			return false
+		}
+		return false
+	}
+}
+
+// LevelFilter returns a Filter that accepts entries with a level of at least
+// min. Entries without a level are treated as LevelNone.
+func LevelFilter(min Level) Filter {
+	return func(ctx context.Context, e *Entry) bool {
+		return e.Level >= min
+	}
+}
+
+// MinLevelFilter returns a Filter that applies a minimum level the way loggers
+// that filter by level do: it accepts entries of at least the level set on the
+// context with WithMinLevel, if there is one, and of at least min otherwise.
+// Entries without a level are always accepted.
+func MinLevelFilter(min Level) Filter {
+	return func(ctx context.Context, e *Entry) bool {
+		if e.Level == LevelNone {
+			return true
+		}
+		if l, ok := MinLevelFromContext(ctx); ok {
+			return e.Level >= l
+		}
+		return e.Level >= min
+	}
+}
+
+// CallerFilter returns a Filter that accepts entries whose caller file matches
+// pattern, using the syntax of path.Match. The pattern is matched against as
+// many trailing path elements as it has, so "billing/*.go" matches every file
+// in any directory named billing.
+//
+// Entries without caller information never match; see WithCaller.
+func CallerFilter(pattern string) Filter {
+	n := strings.Count(pattern, "/") + 1
+	return func(ctx context.Context, e *Entry) bool {
+		if e.File == "" {
+			return false
+		}
+		ok, _ := path.Match(pattern, trailingElements(e.File, n))
+		return ok
+	}
+}
+
+// FilterEmitter returns an Emitter that passes the entries accepted by every
+// filter on to next.
+func FilterEmitter(next Emitter, filters ...Filter) Emitter {
+	return EmitterFunc(func(ctx context.Context, e *Entry) {
+		for _, f := range filters {
+			if !f(ctx, e) {
+				return
+			}
+		}
+		next.Emit(ctx, e)
+	})
+}
+
+// Branch is one of the destinations of an emitter returned by MultiEmitter.
+type Branch struct {
+	// Emitter receives the entries accepted by Filter.
+	Emitter Emitter
+
+	// Filter selects the entries passed on to Emitter. A nil Filter
+	// accepts every entry.
+	Filter Filter
+
+	// Timeout, if positive, is how long the multi-emitter waits for
+	// Emitter before moving on. Entries are dropped for this branch until
+	// the call that timed out returns.
+	//
+	// If Timeout is zero, Emitter is called synchronously.
+	Timeout time.Duration
+}
+
+// States of a branch with a Timeout.
+const (
+	// branchIdle branches are waiting for an entry.
+	branchIdle int32 = iota
+	// branchWaiting branches are emitting an entry, and a caller is
+	// waiting for them.
+	branchWaiting
+	// branchAbandoned branches are emitting an entry, and the caller has
+	// timed out.
+	branchAbandoned
+)
+
+type branch struct {
+	Branch
+
+	// For branches with a Timeout, entries are emitted from the run
+	// goroutine, started when the first entry is emitted, and state
+	// serializes callers: the caller that moves it to branchWaiting owns
+	// timer until it moves it on.
+	state atomic.Int32
+	start sync.Once
+	timer *time.Timer
+	work  chan job
+	done  chan result
+}
+
+// job is an entry for the run goroutine of a branch.
+type job struct {
+	ctx context.Context
+	e   Entry
+}
+
+// result is what the run goroutine of a branch sends back for each job.
+type result struct {
+	// msg is the message of the entry.
+	msg string
+	// recovered is the value of a panic recovered while emitting the
+	// entry.
+	recovered interface{}
+}
+
+type multiEmitter []*branch
+
+// MultiEmitter returns an Emitter that duplicates entries to each branch, in
+// the order the branches are given.
+//
+// Every branch is passed its own copy of the Entry, and a panic in one branch
+// is recovered so it doesn't prevent the other branches from emitting; it's
+// reported as an error with ReportError, from the goroutine that called Emit.
+// Use Branch.Timeout to keep a branch that blocks from holding up the others.
+// Such branches emit from a goroutine of their own, started when they first
+// receive an entry.
+func MultiEmitter(branches ...Branch) Emitter {
+	m := make(multiEmitter, len(branches))
+	for i, b := range branches {
+		m[i] = &branch{Branch: b}
+		if b.Timeout > 0 {
+			m[i].work = make(chan job)
+			m[i].done = make(chan result, 1)
+		}
+	}
+	return m
+}
+
+// Emit implements Emitter.
+func (m multiEmitter) Emit(ctx context.Context, e *Entry) {
+	for _, b := range m {
+		if b.Filter != nil && !b.Filter(ctx, e) {
+			continue
+		}
+		if b.Timeout > 0 {
+			b.emitWithTimeout(ctx, e)
+			continue
+		}
+		entry := *e
+		if p := b.emit(ctx, &entry); p != nil {
+			reportPanic(ctx, &entry, p)
+		}
+	}
+}
+
+// emitWithTimeout hands a copy of e to the run goroutine, and waits for it for
+// up to b.Timeout. The entry is dropped if the branch is still busy with an
+// entry that timed out.
+func (b *branch) emitWithTimeout(ctx context.Context, e *Entry) {
+	for !b.state.CompareAndSwap(branchIdle, branchWaiting) {
+		if b.state.Load() != branchAbandoned {
+			return
+		}
+		select {
+		case r := <-b.done:
+			// The call that timed out has returned, so its panic, if
+			// any, is reported from here.
+			b.state.Store(branchIdle)
+			if r.recovered != nil {
+				reportPanic(ctx, &Entry{Msg: r.msg}, r.recovered)
+			}
+		default:
+			return
+		}
+	}
+
+	b.start.Do(func() {
+		b.timer = time.NewTimer(b.Timeout)
+		go b.run()
+	})
+	b.timer.Reset(b.Timeout)
+	b.work <- job{ctx: ctx, e: *e}
+	select {
+	case r := <-b.done:
+		if !b.timer.Stop() {
+			select {
+			case <-b.timer.C:
+			default:
+			}
+		}
+		b.state.Store(branchIdle)
+		if r.recovered != nil {
+			reportPanic(ctx, e, r.recovered)
+		}
+	case <-b.timer.C:
+		b.state.Store(branchAbandoned)
+	}
+}
+
+func (b *branch) run() {
+	for j := range b.work {
+		p := b.emit(j.ctx, &j.e)
+		b.done <- result{msg: j.e.Msg, recovered: p}
+	}
+}
+
+// emit passes e on to the emitter of the branch, and returns the value of the
+// panic it recovers, if any: a panicking emitter shouldn't take down the other
+// branches.
+func (b *branch) emit(ctx context.Context, e *Entry) (p interface{}) {
+	defer func() { p = recover() }()
+	b.Emitter.Emit(ctx, e)
+	return nil
+}
+
+func reportPanic(ctx context.Context, e *Entry, p interface{}) {
+	ReportError(ctx, nil, e, fmt.Errorf("alog: emitter panicked: %v", p))
+}
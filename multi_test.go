@@ -0,0 +1,182 @@
+package alog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func bufEmitter(b *bytes.Buffer) Emitter {
+	return EmitterFunc(func(ctx context.Context, e *Entry) {
+		fmt.Fprintf(b, "%v %s\n", e.Tags, e.Msg)
+	})
+}
+
+func TestMultiEmitter(t *testing.T) {
+	all, errs := &bytes.Buffer{}, &bytes.Buffer{}
+	l := New(WithEmitter(MultiEmitter(
+		Branch{Emitter: bufEmitter(all)},
+		Branch{Emitter: bufEmitter(errs), Filter: TagFilter("level", "error")},
+	)))
+
+	ctx := context.Background()
+	l.Print(AddTags(ctx, "level", "info"), "info")
+	l.Print(AddTags(ctx, "level", "error"), "error")
+	l.Print(AddTags(ctx, "level", "error", "level", "info"), "relabeled")
+
+	if got, want := all.String(), "[[level info]] info\n[[level error]] error\n[[level info]] relabeled\n"; got != want {
+		t.Errorf("all: got %#q, want %#q", got, want)
+	}
+	if got, want := errs.String(), "[[level error]] error\n"; got != want {
+		t.Errorf("errs: got %#q, want %#q", got, want)
+	}
+}
+
+func TestMultiEmitterCopiesEntry(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := New(WithEmitter(MultiEmitter(
+		Branch{Emitter: EmitterFunc(func(ctx context.Context, e *Entry) { e.Msg = "changed" })},
+		Branch{Emitter: bufEmitter(b)},
+	)))
+
+	l.Print(context.Background(), "test")
+
+	if got, want := b.String(), "[] test\n"; got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+}
+
+func TestMultiEmitterIsolation(t *testing.T) {
+	b := &bytes.Buffer{}
+	block := make(chan struct{})
+	defer close(block)
+	l := New(WithEmitter(MultiEmitter(
+		Branch{Emitter: EmitterFunc(func(ctx context.Context, e *Entry) { panic("boom") })},
+		Branch{
+			Emitter: EmitterFunc(func(ctx context.Context, e *Entry) { <-block }),
+			Timeout: time.Millisecond,
+		},
+		Branch{Emitter: bufEmitter(b)},
+	)))
+
+	ctx := context.Background()
+	l.Print(ctx, "first")
+	l.Print(ctx, "second")
+
+	if got, want := b.String(), "[] first\n[] second\n"; got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+}
+
+func TestMultiEmitterPanicReported(t *testing.T) {
+	var errs []string
+	l := New(
+		WithEmitter(MultiEmitter(
+			Branch{Emitter: EmitterFunc(func(ctx context.Context, e *Entry) { panic("boom") })},
+		)),
+		WithErrorHandler(func(ctx context.Context, e *Entry, err error) {
+			errs = append(errs, e.Msg+": "+err.Error())
+		}),
+	)
+
+	l.Print(context.Background(), "test")
+
+	if len(errs) != 1 || errs[0] != "test: alog: emitter panicked: boom" {
+		t.Errorf("got errors %q", errs)
+	}
+}
+
+func TestMultiEmitterTimeoutPanicReported(t *testing.T) {
+	var errs []string
+	gate := make(chan struct{})
+	l := New(
+		WithEmitter(MultiEmitter(Branch{
+			Emitter: EmitterFunc(func(ctx context.Context, e *Entry) {
+				if e.Msg == "late" {
+					<-gate
+				}
+				panic("boom")
+			}),
+			Timeout: 10 * time.Millisecond,
+		})),
+		WithErrorHandler(func(ctx context.Context, e *Entry, err error) {
+			// Like errorhandler.Panic, this must panic on the goroutine
+			// that logged.
+			panic(e.Msg + ": " + err.Error())
+		}),
+	)
+	print := func(msg string) {
+		defer func() {
+			if p := recover(); p != nil {
+				errs = append(errs, p.(string))
+			}
+		}()
+		l.Print(context.Background(), msg)
+	}
+
+	print("test")
+	print("late")
+	close(gate)
+	// Entries are dropped until the entry that timed out has been emitted.
+	for deadline := time.Now().Add(5 * time.Second); len(errs) < 2 && time.Now().Before(deadline); {
+		print("dropped")
+		time.Sleep(time.Millisecond)
+	}
+	print("test")
+
+	want := "[test: alog: emitter panicked: boom late: alog: emitter panicked: boom test: alog: emitter panicked: boom]"
+	if got := fmt.Sprint(errs); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCallerFilter(t *testing.T) {
+	for _, tbl := range []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"*.go", "/src/billing/charge.go", true},
+		{"billing/*.go", "/src/billing/charge.go", true},
+		{"billing/*.go", "/src/shipping/charge.go", false},
+		{"src/*/charge.go", "/src/billing/charge.go", true},
+		{"charge.go", "charge.go", true},
+		{"*.go", "", false},
+	} {
+		e := &Entry{File: tbl.file}
+		if got := CallerFilter(tbl.pattern)(context.Background(), e); got != tbl.want {
+			t.Errorf("CallerFilter(%q) on %q: got %t, want %t", tbl.pattern, tbl.file, got, tbl.want)
+		}
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := New(WithEmitter(MultiEmitter(Branch{Emitter: bufEmitter(b), Filter: LevelFilter(LevelWarning)})))
+
+	ctx := context.Background()
+	l.Print(ctx, "none")
+	l.Print(WithLevel(ctx, LevelInfo), "info")
+	l.Print(WithLevel(ctx, LevelError), "error")
+
+	if got, want := b.String(), "[] error\n"; got != want {
+		t.Errorf("got %#q, want %#q", got, want)
+	}
+}
+
+func TestMinLevelFilter(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := New(WithEmitter(FilterEmitter(bufEmitter(b), MinLevelFilter(LevelWarning))))
+
+	ctx := context.Background()
+	l.Print(WithLevel(ctx, LevelInfo), "dropped")
+	l.Print(WithLevel(ctx, LevelError), "error")
+	l.Print(ctx, "no level")
+	l.Print(WithMinLevel(WithLevel(ctx, LevelDebug), LevelDebug), "debug by context")
+	l.Print(WithMinLevel(WithLevel(ctx, LevelError), LevelCritical), "dropped by context")
+
+	if got, want := b.String(), "[] error\n[] no level\n[] debug by context\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -32,3 +32,34 @@ func WithCaller() Option {
 func OverrideTimestamp(f func() time.Time) Option {
 	return func(l *Logger) { l.now = f }
 }
+
+// WithErrorHandler configures the logger to report the errors of its emitter
+// to h. Emitters with an error handler of their own use that one instead.
+//
+// See ReportError.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(l *Logger) { l.errorHandler = h }
+}
+
+// WithFilter configures the logger to drop the entries f rejects. Unlike a
+// FilterEmitter, f is called before the entry is complete: it sees the time,
+// level, tags and caller of the entry, but its lazy tags are only resolved, and
+// its stack only captured, once f has accepted it. That makes it the cheapest
+// place to filter entries by level.
+//
+// Filters that match the caller, such as CallerFilter, need WithCaller.
+func WithFilter(f Filter) Option {
+	return func(l *Logger) { l.filter = f }
+}
+
+// WithStack configures the logger to capture a stack trace for each entry with
+// a level of at least min. Use LevelNone to capture one for every entry, or
+// LevelError for only errors and worse.
+//
+// Stack traces can also be captured for individual entries with CaptureStack.
+func WithStack(min Level) Option {
+	return func(l *Logger) {
+		l.stack = true
+		l.stackLevel = min
+	}
+}
@@ -0,0 +1,64 @@
+// Package requestid carries the ID of the request being handled in a context,
+// and tags the entries logged with that context with it.
+//
+// It's shared by the HTTP middleware in httplog and the gRPC interceptors in
+// grpcalog, so a request ID set by one is sent on by the other.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// Tag is the key of the tag holding the request ID.
+const Tag = "request_id"
+
+// maxLength is the length of the longest request ID accepted from a client.
+const maxLength = 128
+
+type ctxKey struct{}
+
+// WithID returns a copy of parent with the ID of the request being handled,
+// which is also added as a Tag tag.
+func WithID(parent context.Context, id string) context.Context {
+	ctx := context.WithValue(parent, ctxKey{}, id)
+	return alog.AddTags(ctx, Tag, id)
+}
+
+// FromContext returns the ID of the request being handled, as set by WithID,
+// or "" if there isn't one.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Valid reports whether id can be used as a request ID sent by a client. It
+// must be 1 to 128 bytes of ASCII letters, digits, and the characters
+// "-_.:+/=", which covers UUIDs and encoded random bytes, so clients can't put
+// arbitrary text in the tags of every entry.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("-_.:+/=", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// New returns a new request ID, made of 16 random bytes, hex encoded.
+func New() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
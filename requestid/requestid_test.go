@@ -0,0 +1,47 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vimeo/alog/v3"
+)
+
+func TestWithID(t *testing.T) {
+	ctx := context.Background()
+	if got := FromContext(ctx); got != "" {
+		t.Errorf("FromContext = %q", got)
+	}
+
+	ctx = WithID(ctx, "abc")
+	if got := FromContext(ctx); got != "abc" {
+		t.Errorf("FromContext = %q", got)
+	}
+	if got, _ := alog.TagValue(ctx, Tag); got != "abc" {
+		t.Errorf("%s tag = %q", Tag, got)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for id, want := range map[string]bool{
+		"f47ac10b-58cc-4372-a567-0e02b2c3d479":     true,
+		"Root=1-5759e988-bd862e3fe1be46a994272793": true,
+		"YWJj+/==":               true,
+		"":                       false,
+		strings.Repeat("a", 129): false,
+		"a b":                    false,
+		"a\nb":                   false,
+		"é":                      false,
+	} {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if id := New(); len(id) != 32 || id == New() || !Valid(id) {
+		t.Errorf("New() = %q", id)
+	}
+}
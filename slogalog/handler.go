@@ -0,0 +1,142 @@
+// Package slogalog provides a slog.Handler that emits records through an
+// *alog.Logger, so code written against log/slog shares the output of the
+// alog emitters.
+package slogalog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// Handler is a slog.Handler that turns each slog.Record into an alog.Entry.
+//
+// Attributes with string values become Tags, and all other attributes become
+// STags. Groups are flattened into the attribute keys, joined with a ".".
+// Tags already in the context (see alog.AddTags and alog.AddStructuredTags)
+// are kept, and attributes with the same key take precedence over them.
+type Handler struct {
+	logger *alog.Logger
+	o      *Options
+
+	// prefix is prepended to every attribute key, and is the dot-joined list
+	// of groups opened by WithGroup.
+	prefix string
+	tags   [][2]string
+	sTags  []alog.STag
+}
+
+// NewHandler returns a Handler that emits records through logger.
+func NewHandler(logger *alog.Logger, opt ...Option) *Handler {
+	o := &Options{
+		level:     slog.LevelInfo,
+		levelFunc: SetLevel,
+	}
+	for _, option := range opt {
+		option(o)
+	}
+	return &Handler{
+		logger: logger,
+		o:      o,
+	}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger != nil && level >= h.o.level.Level()
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tags, sTags := h.tags, h.sTags
+	if r.NumAttrs() > 0 {
+		tags = tags[:len(tags):len(tags)]
+		sTags = sTags[:len(sTags):len(sTags)]
+		r.Attrs(func(a slog.Attr) bool {
+			tags, sTags = appendAttr(tags, sTags, h.prefix, a)
+			return true
+		})
+	}
+	if len(tags) > 0 {
+		ctx = alog.AddTags(ctx, flatten(tags)...)
+	}
+	if len(sTags) > 0 {
+		ctx = alog.AddStructuredTags(ctx, sTags...)
+	}
+	ctx = h.o.levelFunc(ctx, r.Level)
+
+	h.logger.OutputPC(ctx, r.PC, r.Message)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.tags = h.tags[:len(h.tags):len(h.tags)]
+	h2.sTags = h.sTags[:len(h.sTags):len(h.sTags)]
+	for _, a := range attrs {
+		h2.tags, h2.sTags = appendAttr(h2.tags, h2.sTags, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// appendAttr adds a to either tags or sTags, depending on the kind of its
+// value, and returns the updated slices. Group values are flattened.
+func appendAttr(tags [][2]string, sTags []alog.STag, prefix string, a slog.Attr) ([][2]string, []alog.STag) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return tags, sTags
+	}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return tags, sTags
+		}
+		// A group with an empty key has its attributes inlined, as
+		// documented on slog.Handler.
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range attrs {
+			tags, sTags = appendAttr(tags, sTags, prefix, ga)
+		}
+	case slog.KindString, slog.KindDuration:
+		tags = append(tags, [2]string{prefix + a.Key, a.Value.String()})
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			tags = append(tags, [2]string{prefix + a.Key, err.Error()})
+			break
+		}
+		sTags = append(sTags, alog.STag{Key: prefix + a.Key, Val: a.Value.Any()})
+	default:
+		sTags = append(sTags, alog.STag{Key: prefix + a.Key, Val: a.Value.Any()})
+	}
+	return tags, sTags
+}
+
+// flatten turns tags into the key-value pairs that alog.AddTags takes.
+func flatten(tags [][2]string) []string {
+	pairs := make([]string, 0, 2*len(tags))
+	for _, t := range tags {
+		pairs = append(pairs, t[0], t[1])
+	}
+	return pairs
+}
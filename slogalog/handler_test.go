@@ -0,0 +1,106 @@
+package slogalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+	"github.com/vimeo/alog/v3/emitter/jsonlog"
+)
+
+var zeroTimeOpt = alog.OverrideTimestamp(func() time.Time { return time.Time{} })
+
+// Keep this function at the top of the file so that the line number doesn't change too often
+func TestCaller(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithCaller(), alog.WithEmitter(jsonlog.Emitter(b, jsonlog.WithDateFormat(""), jsonlog.WithShortFile())))
+	sl := slog.New(NewHandler(l))
+
+	sl.Info("test")
+
+	want := `{"level":"info", "caller":"handler_test.go:24", "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(jsonlog.Emitter(b, jsonlog.WithDateFormat(""))))
+	sl := slog.New(NewHandler(l))
+
+	ctx := alog.AddTags(context.Background(), "from", "ctx")
+	sl.InfoContext(ctx, "test", "str", "v", "n", 42, "d", time.Second, "err", errors.New("boom"))
+
+	want := `{"level":"info", "tags":{"from":"ctx", "str":"v", "d":"1s", "err":"boom"}, "sTags":{"n":42}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(jsonlog.Emitter(b, jsonlog.WithDateFormat(""))))
+	sl := slog.New(NewHandler(l)).With("a", "1").WithGroup("g").With("b", "2")
+
+	sl.Warn("test", slog.Group("h", "c", "3"), slog.Group("", "d", "4"), slog.Group("empty"))
+
+	want := `{"level":"warning", "tags":{"a":"1", "g.b":"2", "g.h.c":"3", "g.d":"4"}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestOverrideContextTag(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(jsonlog.Emitter(b, jsonlog.WithDateFormat(""))))
+	sl := slog.New(NewHandler(l))
+
+	ctx := alog.AddTags(context.Background(), "a", "ctx")
+	sl.ErrorContext(ctx, "test", "a", "attr")
+
+	want := `{"level":"error", "tags":{"a":"attr"}, "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLevel(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(jsonlog.Emitter(b, jsonlog.WithDateFormat(""))))
+	sl := slog.New(NewHandler(l, WithLevel(slog.LevelWarn)))
+
+	sl.Info("not logged")
+	sl.Log(context.Background(), slog.LevelError+4, "logged")
+
+	want := `{"level":"critical", "message":"logged"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGKESeverity(t *testing.T) {
+	b := &bytes.Buffer{}
+	l := alog.New(alog.WithEmitter(gkelog.Emitter(gkelog.WithWriter(b))), zeroTimeOpt)
+	sl := slog.New(NewHandler(l))
+
+	sl.Warn("test", "a", "b")
+
+	want := `{"time":"0001-01-01T00:00:00Z", "severity":"WARNING", "a":"b", "message":"test"}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	sl := slog.New(NewHandler(nil))
+	if sl.Enabled(context.Background(), slog.LevelError) {
+		t.Error("nil logger reported as enabled")
+	}
+	sl.Error("this shouldn't explode")
+}
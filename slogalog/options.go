@@ -0,0 +1,62 @@
+package slogalog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/vimeo/alog/v3"
+)
+
+// Options holds option values.
+type Options struct {
+	level     slog.Leveler
+	levelFunc LevelFunc
+}
+
+// Option sets an option for the Handler.
+//
+// Options are applied in the order specified.
+type Option func(*Options)
+
+// LevelFunc records the level of a slog.Record in the context passed on to the
+// alog.Logger.
+type LevelFunc func(ctx context.Context, level slog.Level) context.Context
+
+// WithLevel sets the minimum level the Handler reports as enabled. Records
+// below it are discarded by slog before they reach the Handler.
+//
+// If this option is not specified, slog.LevelInfo is used.
+func WithLevel(level slog.Leveler) Option {
+	return func(o *Options) { o.level = level }
+}
+
+// WithLevelFunc sets how record levels are attached to the context.
+//
+// If this option is not specified, SetLevel is used.
+func WithLevelFunc(f LevelFunc) Option {
+	return func(o *Options) { o.levelFunc = f }
+}
+
+// Level maps a slog.Level to the closest alog.Level. Levels between slog's
+// named levels round down, and levels above slog.LevelError map to
+// alog.LevelCritical.
+func Level(level slog.Level) alog.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return alog.LevelDebug
+	case level < slog.LevelWarn:
+		return alog.LevelInfo
+	case level < slog.LevelError:
+		return alog.LevelWarning
+	case level == slog.LevelError:
+		return alog.LevelError
+	default:
+		return alog.LevelCritical
+	}
+}
+
+// SetLevel sets the level of the entry with alog.WithLevel, which the bundled
+// emitters render natively.
+func SetLevel(ctx context.Context, level slog.Level) context.Context {
+	return alog.WithLevel(ctx, Level(level))
+}
@@ -0,0 +1,95 @@
+package alog
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+)
+
+type stackKey struct{}
+
+var stackCtxKey = stackKey{}
+
+// maxStackDepth is the maximum number of frames captured for an entry.
+const maxStackDepth = 64
+
+// Frame is a single frame of a stack trace.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+
+	// Offset is the offset of the return address from the start of
+	// Function, as shown in Go tracebacks, or 0 if the call was inlined.
+	Offset uintptr
+}
+
+// CaptureStack returns a copy of parent that makes the Logger capture a stack
+// trace for the entries logged with it, regardless of their level.
+func CaptureStack(parent context.Context) context.Context {
+	return context.WithValue(parent, stackCtxKey, true)
+}
+
+// wantStack reports whether a stack trace should be captured for e.
+func (l *Logger) wantStack(ctx context.Context, e *Entry) bool {
+	if l.stack && e.Level >= l.stackLevel {
+		return true
+	}
+	capture, _ := ctx.Value(stackCtxKey).(bool)
+	return capture
+}
+
+// callers returns the program counters of the stack, skipping skip frames
+// like runtime.Callers does.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	return pcs[:runtime.Callers(skip+1, pcs)]
+}
+
+// framesFrom returns the frames of pcs, starting from the one for pc. If pc
+// isn't in pcs, all of them are returned.
+func framesFrom(pcs []uintptr, pc uintptr) []uintptr {
+	for i, p := range pcs {
+		if p == pc {
+			return pcs[i:]
+		}
+	}
+	return pcs
+}
+
+// stackFrames resolves pcs into Frames.
+func stackFrames(pcs []uintptr) []Frame {
+	if len(pcs) == 0 {
+		return nil
+	}
+	stack := make([]Frame, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		frame := Frame{Function: f.Function, File: f.File, Line: f.Line}
+		if f.Func != nil {
+			// f.PC is the return address minus one.
+			frame.Offset = f.PC + 1 - f.Entry
+		}
+		stack = append(stack, frame)
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// goroutineID returns the ID of the calling goroutine, as shown in Go
+// tracebacks, or 0 if it can't be found.
+func goroutineID() uint64 {
+	// The traceback starts with "goroutine 1 [running]:".
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
@@ -0,0 +1,139 @@
+package alog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// tagOp is the change a tagNode makes to the tags of its parent.
+type tagOp uint8
+
+const (
+	// opAdd removes the parent's tags that have the keys of the node's
+	// tags, and appends the node's tags.
+	opAdd tagOp = iota
+	// opReplace sets the values of the parent's tags that have the keys of
+	// the node's tags in place, and appends the others.
+	opReplace
+	// opRemove removes the parent's tags with the node's keys.
+	opRemove
+)
+
+// tagNode is one version of a list of tags stored in a Context. Each call that
+// changes the tags adds a node that records the change and points to the
+// previous version, so adding a tag doesn't copy the tags that were already
+// there. The flattened list is only built when an entry is logged, and is
+// cached on the node for later entries.
+//
+// A nil *tagNode is an empty list.
+type tagNode[T any] struct {
+	parent *tagNode[T]
+	op     tagOp
+	tags   []T      // for opAdd and opReplace; keys are unique
+	keys   []string // for opRemove
+
+	once   sync.Once
+	cached atomic.Bool // set once flat is
+	flat   []T
+}
+
+// with returns a node that applies op to n.
+func (n *tagNode[T]) with(op tagOp, tags []T, keys []string) *tagNode[T] {
+	return &tagNode[T]{parent: n, op: op, tags: tags, keys: keys}
+}
+
+// list returns the tags of n. The result is shared, and must not be modified.
+func (n *tagNode[T]) list(key func(T) string) []T {
+	if n == nil {
+		return nil
+	}
+	n.once.Do(func() { n.setFlat(n.flatten(key)) })
+	return n.flat
+}
+
+func (n *tagNode[T]) setFlat(flat []T) {
+	n.flat = flat
+	n.cached.Store(true)
+}
+
+// flatten builds the list of tags of n. The nodes back to the closest one with
+// a cached list are applied to a copy of its list. The list of the parent of n
+// is cached along the way, so that siblings of n don't have to walk the chain
+// again.
+func (n *tagNode[T]) flatten(key func(T) string) []T {
+	var buf [16]*tagNode[T]
+	chain := buf[:0]
+	var base []T
+	size := 0
+	for m := n; m != nil; m = m.parent {
+		if m.cached.Load() {
+			base = m.flat
+			break
+		}
+		chain = append(chain, m)
+		size += len(m.tags)
+	}
+
+	if len(chain) > 1 {
+		parent := make([]T, len(base), len(base)+size-len(n.tags))
+		copy(parent, base)
+		for i := len(chain) - 1; i > 0; i-- {
+			parent = chain[i].apply(parent, key)
+		}
+		p := chain[1]
+		p.once.Do(func() { p.setFlat(parent) })
+		base = p.flat
+	}
+
+	if len(base) == 0 && n.op != opRemove {
+		// n.tags has unique keys, so there's nothing to apply.
+		return n.tags
+	}
+	flat := make([]T, len(base), len(base)+len(n.tags))
+	copy(flat, base)
+	return n.apply(flat, key)
+}
+
+// apply makes the change recorded in n to tags, which it may modify.
+func (n *tagNode[T]) apply(tags []T, key func(T) string) []T {
+	switch n.op {
+	case opAdd:
+		for _, t := range n.tags {
+			if i := indexKey(tags, key(t), key); i >= 0 {
+				tags = append(tags[:i], tags[i+1:]...)
+			}
+		}
+		return append(tags, n.tags...)
+	case opReplace:
+		for _, t := range n.tags {
+			if i := indexKey(tags, key(t), key); i >= 0 {
+				tags[i] = t
+				continue
+			}
+			tags = append(tags, t)
+		}
+		return tags
+	case opRemove:
+		out := tags[:0]
+		for _, t := range tags {
+			if !containsKey(n.keys, key(t)) {
+				out = append(out, t)
+			}
+		}
+		return out
+	}
+	return tags
+}
+
+func indexKey[T any](tags []T, k string, key func(T) string) int {
+	for i, t := range tags {
+		if key(t) == k {
+			return i
+		}
+	}
+	return -1
+}
+
+func tagKey(t [2]string) string   { return t[0] }
+func sTagKey(t STag) string       { return t.Key }
+func lazyTagKey(t LazyTag) string { return t.Key }
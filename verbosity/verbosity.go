@@ -0,0 +1,112 @@
+// Package verbosity raises the verbosity of logging for selected requests, so
+// that one customer's requests can be debugged in production without turning
+// on debug logging for everyone.
+//
+// When one of its Triggers fires, Middleware sets a minimum level on the
+// request's context with alog.WithMinLevel, which leveled.Filtered loggers and
+// gkelog's Log functions honor in place of their own.
+package verbosity
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+)
+
+// DefaultHeader is the header checked by Header triggers if WithHeaderName is
+// not specified.
+const DefaultHeader = "X-Debug-Log"
+
+// Trigger reports whether logging should be more verbose for a request. r is
+// nil outside of HTTP servers, such as in a gRPC interceptor.
+type Trigger func(ctx context.Context, r *http.Request) bool
+
+// Header returns a Trigger that fires for HTTP requests whose DefaultHeader
+// header is set to secret. The comparison takes constant time, so the secret
+// can't be guessed from response times. An empty secret never matches.
+//
+// The header is removed from the request once it has been checked, so that the
+// secret isn't logged along with the request, as gkelog does with its headers.
+func Header(secret string) Trigger {
+	return HeaderName(DefaultHeader, secret)
+}
+
+// HeaderName is like Header, for a header called name.
+func HeaderName(name, secret string) Trigger {
+	return func(ctx context.Context, r *http.Request) bool {
+		if r == nil {
+			return false
+		}
+		values := r.Header.Values(name)
+		r.Header.Del(name)
+		if secret == "" {
+			return false
+		}
+		for _, v := range values {
+			if subtle.ConstantTimeCompare([]byte(v), []byte(secret)) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Tag returns a Trigger that fires when the context has a tag with key set to
+// one of values, such as the ID of a user whose requests are being debugged.
+// The tag must have been added before the trigger is checked.
+func Tag(key string, values ...string) Trigger {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return func(ctx context.Context, r *http.Request) bool {
+		v, ok := alog.TagValue(ctx, key)
+		return ok && set[v]
+	}
+}
+
+// SampledTrace returns a Trigger that fires for requests in a trace that
+// extract reports as sampled, such as the extractors in
+// gkelog/traceextractors. Tracing middleware has to have run first.
+func SampledTrace(extract gkelog.TraceSpanExtractor) Trigger {
+	return func(ctx context.Context, r *http.Request) bool {
+		sc := extract(ctx)
+		return sc.Sampled && sc.TraceID != ""
+	}
+}
+
+// Context returns a copy of ctx with its minimum level set to level if any of
+// triggers fires for it and r, which may be nil. Otherwise ctx is returned
+// as is.
+//
+// Every trigger is checked, even once one has fired, so that Header triggers
+// always remove their header from r.
+func Context(ctx context.Context, r *http.Request, level alog.Level, triggers ...Trigger) context.Context {
+	fired := false
+	for _, t := range triggers {
+		if t(ctx, r) {
+			fired = true
+		}
+	}
+	if fired {
+		return alog.WithMinLevel(ctx, level)
+	}
+	return ctx
+}
+
+// Middleware returns HTTP middleware that logs the requests for which any of
+// triggers fires at level and above, by calling Context with the request.
+func Middleware(level alog.Level, triggers ...Trigger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := Context(r.Context(), r, level, triggers...)
+			if ctx != r.Context() {
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package verbosity
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vimeo/alog/v3"
+	"github.com/vimeo/alog/v3/emitter/gkelog"
+	"github.com/vimeo/alog/v3/emitter/textlog"
+	"github.com/vimeo/alog/v3/httplog"
+	"github.com/vimeo/alog/v3/leveled"
+)
+
+func TestMiddleware(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := alog.New(alog.WithEmitter(textlog.Emitter(b)))
+	l := leveled.Filtered(logger)
+	l.SetMinLevel(leveled.Info)
+
+	h := Middleware(alog.LevelDebug, Header("s3cret"), Tag("user", "42"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Debug(r.Context(), "leveled %s", r.URL.Path)
+		gkelog.LogDebug(r.Context(), logger, "gkelog %s", r.URL.Path)
+	}))
+	// gkelog logs everything unless the context sets a minimum.
+	withUser := func(user string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r = r.WithContext(gkelog.WithMinSeverity(r.Context(), gkelog.SeverityInfo))
+				ctx := r.Context()
+				if user != "" {
+					ctx = alog.AddTags(ctx, "user", user)
+				}
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
+		}
+	}
+
+	for _, tt := range []struct {
+		path   string
+		header string
+		user   string
+	}{
+		{"/plain", "", ""},
+		{"/header", "s3cret", ""},
+		{"/wrong-header", "guess", ""},
+		{"/user", "", "42"},
+		{"/other-user", "", "7"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.header != "" {
+			r.Header.Set(DefaultHeader, tt.header)
+		}
+		withUser(tt.user)(h).ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	const want = "DEBUG     leveled /header\nDEBUG     gkelog /header\n" +
+		"DEBUG     [user=42] leveled /user\nDEBUG     [user=42] gkelog /user\n"
+	if got := b.String(); got != want {
+		t.Errorf("got: %#q, want: %#q", got, want)
+	}
+}
+
+func TestHeaderName(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add("X-Verbose", "a")
+	r.Header.Add("X-Verbose", "b")
+	ctx := context.Background()
+	if !HeaderName("X-Verbose", "b")(ctx, r) {
+		t.Error("second header value didn't match")
+	}
+	if HeaderName("X-Verbose", "")(ctx, r) || HeaderName("X-Verbose", "b")(ctx, nil) {
+		t.Error("empty secret or nil request matched")
+	}
+	if v := r.Header.Values("X-Verbose"); len(v) != 0 {
+		t.Errorf("header left on the request: %q", v)
+	}
+}
+
+func TestSecretNotLogged(t *testing.T) {
+	b := &bytes.Buffer{}
+	logger := alog.New(alog.WithEmitter(gkelog.Emitter(gkelog.WithWriter(b))))
+
+	// The access log entry is written with the request httplog saw, before
+	// Middleware ran.
+	h := httplog.Middleware(logger)(Middleware(alog.LevelDebug, Tag("user", "42"), Header("s3cret"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gkelog.LogDebug(r.Context(), logger, "handling")
+	})))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(DefaultHeader, "s3cret")
+	r.Header.Set("X-Other", "kept")
+	h.ServeHTTP(httptest.NewRecorder(), r.WithContext(alog.AddTags(r.Context(), "user", "42")))
+
+	if got := b.String(); strings.Count(got, "\n") != 2 || strings.Contains(got, "s3cret") || !strings.Contains(got, "kept") {
+		t.Errorf("got:\n%s", got)
+	}
+}
+
+type sampledKey struct{}
+
+func TestSampledTrace(t *testing.T) {
+	extract := func(ctx context.Context) gkelog.SpanContext {
+		sampled, _ := ctx.Value(sampledKey{}).(bool)
+		return gkelog.SpanContext{TraceID: "abc", Sampled: sampled}
+	}
+	trigger := SampledTrace(extract)
+
+	ctx := Context(context.WithValue(context.Background(), sampledKey{}, true), nil, alog.LevelInfo, trigger)
+	if l, ok := alog.MinLevelFromContext(ctx); !ok || l != alog.LevelInfo {
+		t.Errorf("sampled trace: got %v, %v", l, ok)
+	}
+	ctx = Context(context.Background(), nil, alog.LevelInfo, trigger)
+	if _, ok := alog.MinLevelFromContext(ctx); ok {
+		t.Error("unsampled trace triggered")
+	}
+}
@@ -0,0 +1,198 @@
+package alog
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// VModule holds minimum level overrides for the callers in particular files or
+// packages, in the style of glog's -vmodule flag. Loggers that filter by level,
+// such as leveled.Filtered and Loggers with gkelog.VModuleFilter, use the
+// override for a call site in place of their usual minimum level, unless the
+// context has a minimum level set with WithMinLevel.
+//
+// The zero value has no overrides. A VModule is safe for concurrent use, and
+// implements flag.Value so it can be set from the command line.
+type VModule struct {
+	state atomic.Pointer[vmoduleState]
+}
+
+type vmoduleState struct {
+	spec  string
+	rules []vmoduleRule
+
+	mu    sync.RWMutex
+	sites map[uintptr]siteLevel
+	files map[string]siteLevel
+}
+
+type vmoduleRule struct {
+	pattern string
+	n       int // the number of path elements in pattern
+	level   Level
+}
+
+type siteLevel struct {
+	level Level
+	ok    bool
+}
+
+// ParseVModule returns a VModule with the overrides in spec. See Set.
+func ParseVModule(spec string) (*VModule, error) {
+	m := new(VModule)
+	if err := m.Set(spec); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Set replaces the overrides with those in spec, a comma separated list of
+// pattern=level pairs such as "gkelog=debug,billing/*=info".
+//
+// A pattern without a slash matches the files with that name, without the
+// ".go" suffix, and the files in directories with that name. A pattern with
+// slashes is matched against as many trailing path elements as it has, again
+// without the ".go" suffix, so "billing/*" matches every file in any directory
+// named billing. Patterns use the syntax of path.Match, and the first one that
+// matches is used. Levels are parsed with ParseLevel.
+func (m *VModule) Set(spec string) error {
+	var rules []vmoduleRule
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		pattern, name, ok := strings.Cut(pair, "=")
+		if !ok || pattern == "" {
+			return fmt.Errorf("vmodule: invalid override %q", pair)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("vmodule: invalid pattern %q: %w", pattern, err)
+		}
+		level, err := ParseLevel(strings.TrimSpace(name))
+		if err != nil {
+			return fmt.Errorf("vmodule: %w", err)
+		}
+		rules = append(rules, vmoduleRule{
+			pattern: pattern,
+			n:       strings.Count(pattern, "/") + 1,
+			level:   level,
+		})
+	}
+	m.state.Store(&vmoduleState{spec: spec, rules: rules})
+	return nil
+}
+
+// String returns the spec last passed to Set.
+func (m *VModule) String() string {
+	if m == nil {
+		return ""
+	}
+	if s := m.state.Load(); s != nil {
+		return s.spec
+	}
+	return ""
+}
+
+// MinLevel returns the override for the call site at pc, a program counter as
+// returned by runtime.Callers, and whether there is one. The result is cached
+// for each call site until the overrides are changed.
+//
+// MinLevel on a nil *VModule reports no override.
+func (m *VModule) MinLevel(pc uintptr) (Level, bool) {
+	if m == nil {
+		return LevelNone, false
+	}
+	s := m.state.Load()
+	if s == nil || len(s.rules) == 0 {
+		return LevelNone, false
+	}
+
+	s.mu.RLock()
+	site, ok := s.sites[pc]
+	s.mu.RUnlock()
+	if ok {
+		return site.level, site.ok
+	}
+
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	site.level, site.ok = s.match(f.File)
+
+	s.mu.Lock()
+	if s.sites == nil {
+		s.sites = make(map[uintptr]siteLevel)
+	}
+	s.sites[pc] = site
+	s.mu.Unlock()
+	return site.level, site.ok
+}
+
+// MinLevelForFile is like MinLevel, but takes the caller's file name, as in
+// Entry.File, in place of a program counter. It's meant for emitters and
+// filters, which only see the entry.
+func (m *VModule) MinLevelForFile(file string) (Level, bool) {
+	if m == nil {
+		return LevelNone, false
+	}
+	s := m.state.Load()
+	if s == nil || len(s.rules) == 0 {
+		return LevelNone, false
+	}
+
+	s.mu.RLock()
+	site, ok := s.files[file]
+	s.mu.RUnlock()
+	if ok {
+		return site.level, site.ok
+	}
+
+	site.level, site.ok = s.match(file)
+
+	s.mu.Lock()
+	if s.files == nil {
+		s.files = make(map[string]siteLevel)
+	}
+	s.files[file] = site
+	s.mu.Unlock()
+	return site.level, site.ok
+}
+
+// match returns the level of the first rule matching file.
+func (s *vmoduleState) match(file string) (Level, bool) {
+	if file == "" {
+		return LevelNone, false
+	}
+	file = strings.TrimSuffix(file, ".go")
+	for _, r := range s.rules {
+		if r.n == 1 {
+			dir, base := path.Split(file)
+			if ok, _ := path.Match(r.pattern, base); ok {
+				return r.level, true
+			}
+			if ok, _ := path.Match(r.pattern, path.Base(dir)); ok && dir != "" {
+				return r.level, true
+			}
+			continue
+		}
+		if ok, _ := path.Match(r.pattern, trailingElements(file, r.n)); ok {
+			return r.level, true
+		}
+	}
+	return LevelNone, false
+}
+
+// trailingElements returns the last n slash separated elements of p.
+func trailingElements(p string, n int) string {
+	for i, c := len(p)-1, 0; i >= 0; i-- {
+		if p[i] == '/' {
+			if c++; c == n {
+				return p[i+1:]
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,92 @@
+package alog
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestParseVModule(t *testing.T) {
+	for _, spec := range []string{"", " ", "a=debug", "a=debug, b/*=INFO,", "*_test=none"} {
+		if _, err := ParseVModule(spec); err != nil {
+			t.Errorf("ParseVModule(%q): %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"a", "=debug", "a=verbose", "[=debug"} {
+		if _, err := ParseVModule(spec); err == nil {
+			t.Errorf("ParseVModule(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestVModuleMatch(t *testing.T) {
+	m, err := ParseVModule("gkelog=debug,billing/*=warning,*_test=error,a/b/c=critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := m.state.Load()
+	for _, tt := range []struct {
+		file  string
+		level Level
+		ok    bool
+	}{
+		{"/src/alog/emitter/gkelog/emitter.go", LevelDebug, true},
+		{"/src/alog/emitter/gkelog.go", LevelDebug, true},
+		{"/src/billing/invoice.go", LevelWarning, true},
+		{"/src/billing/sub/invoice.go", LevelNone, false},
+		{"/src/gkelog/emitter_test.go", LevelDebug, true},
+		{"/src/jsonlog/emitter_test.go", LevelError, true},
+		{"/x/a/b/c.go", LevelCritical, true},
+		{"/x/a/b/d.go", LevelNone, false},
+		{"/src/other/gkelogs.go", LevelNone, false},
+		{"", LevelNone, false},
+	} {
+		level, ok := s.match(tt.file)
+		if level != tt.level || ok != tt.ok {
+			t.Errorf("match(%q) = %v, %v, want %v, %v", tt.file, level, ok, tt.level, tt.ok)
+		}
+	}
+}
+
+func TestVModuleMinLevel(t *testing.T) {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+
+	var nilModule *VModule
+	if _, ok := nilModule.MinLevel(pcs[0]); ok {
+		t.Error("override from a nil VModule")
+	}
+	var m VModule
+	if _, ok := m.MinLevel(pcs[0]); ok {
+		t.Error("override from the zero VModule")
+	}
+
+	m.Set("vmodule_test=notice")
+	for i := 0; i < 2; i++ { // the second time is cached
+		if level, ok := m.MinLevel(pcs[0]); level != LevelNotice || !ok {
+			t.Errorf("got %v, %v, want notice", level, ok)
+		}
+	}
+	m.Set("*=alert")
+	if level, ok := m.MinLevel(pcs[0]); level != LevelAlert || !ok {
+		t.Errorf("after Set: got %v, %v, want alert", level, ok)
+	}
+	if got := m.String(); got != "*=alert" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestVModuleMinLevelForFile(t *testing.T) {
+	m, err := ParseVModule("billing=info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ { // the second time is cached
+		if level, ok := m.MinLevelForFile("/src/billing/invoice.go"); level != LevelInfo || !ok {
+			t.Errorf("got %v, %v, want info", level, ok)
+		}
+	}
+	m.Set("")
+	if level, ok := m.MinLevelForFile("/src/billing/invoice.go"); ok {
+		t.Errorf("after Set: got %v, want no override", level)
+	}
+}